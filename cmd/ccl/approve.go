@@ -2,12 +2,9 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/scottstav/wreccless/internal/config"
-	"github.com/scottstav/wreccless/internal/hooks"
 	"github.com/scottstav/wreccless/internal/state"
 	"github.com/scottstav/wreccless/internal/worker"
 	"github.com/spf13/cobra"
@@ -25,35 +22,42 @@ func init() {
 }
 
 func runApprove(cmd *cobra.Command, args []string) error {
-	id := args[0]
-	w, err := state.Read(stateDir, id)
+	id, err := state.Resolve(stateDir, args[0])
 	if err != nil {
-		return fmt.Errorf("worker %s not found", id)
+		return err
 	}
-	if w.Status != state.StatusPending {
-		return fmt.Errorf("worker %s is %s, not pending", id, w.Status)
+	w, err := state.Read(stateDir, id)
+	if err != nil {
+		return err
 	}
-
-	cfg, _ := config.Load(configPath)
-
-	now := time.Now()
-	w.Status = state.StatusWorking
-	w.StartedAt = &now
-	if w.SessionID == "" {
-		w.SessionID = uuid.New().String()
+	cfg, err := config.LoadFor(configPath, w.Directory)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
 	}
-	if err := state.Write(stateDir, w); err != nil {
+	warnIgnored(cmd, cfg)
+
+	w, err = state.Transition(stateDir, id, []state.Status{state.StatusPending}, func(w *state.Worker) error {
+		if len(w.After) > 0 {
+			w.Status = state.StatusBlocked
+			return nil
+		}
+		now := time.Now()
+		w.Status = state.StatusQueued
+		w.QueuedAt = &now
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	state.AppendEvent(stateDir, id, state.Event{Type: state.EventApproved, Actor: state.Actor("cli")})
 
-	cclBin, _ := os.Executable()
-	if err := worker.SpawnRun(id, cclBin, configPath, stateDir); err != nil {
-		return fmt.Errorf("spawn worker: %w", err)
+	if w, err = worker.Schedule(stateDir, configPath, cfg, id); err != nil {
+		return err
+	}
+	if w.Status == state.StatusQueued || w.Status == state.StatusBlocked {
+		fmt.Fprintf(cmd.OutOrStdout(), "Approved worker %s (%s)\n", id, w.Status)
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Approved worker %s\n", id)
 	}
-
-	vars := hooks.Vars{ID: id, Task: w.Task, Dir: w.Directory, Status: string(w.Status)}
-	hooks.Fire(cfg.Hooks.OnStart, vars)
-
-	fmt.Fprintf(cmd.OutOrStdout(), "Approved worker %s\n", id)
 	return nil
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -56,9 +57,12 @@ func TestDeny(t *testing.T) {
 	rootCmd.SetOut(buf)
 	rootCmd.Execute()
 
-	_, err := state.Read(dir, "700")
-	if err == nil {
-		t.Error("worker should be deleted after deny")
+	w, err := state.Read(dir, "700")
+	if err != nil {
+		t.Fatalf("worker should be kept after deny: %v", err)
+	}
+	if w.Status != state.StatusDenied {
+		t.Errorf("expected denied, got %s", w.Status)
 	}
 }
 
@@ -77,3 +81,43 @@ func TestDenyNotPending(t *testing.T) {
 		t.Fatal("expected error when denying non-pending worker")
 	}
 }
+
+func TestApproveTwice(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
+	state.Write(dir, &state.Worker{ID: "602", Status: state.StatusPending, Directory: "/tmp", Task: "once"})
+
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs([]string{"approve", "602"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("first approve: %v", err)
+	}
+	rootCmd.SetArgs([]string{"approve", "602"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Fatal("second approve should be rejected")
+	}
+}
+
+func TestApproveDenyBadConfig(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(configPath, []byte("[worker\n"), 0644)
+	state.Write(dir, &state.Worker{ID: "603", Status: state.StatusPending, Directory: "/tmp", Task: "t"})
+
+	for _, action := range []string{"approve", "deny"} {
+		rootCmd.SetArgs([]string{action, "603"})
+		buf := new(strings.Builder)
+		rootCmd.SetOut(buf)
+		rootCmd.SetErr(buf)
+		if err := rootCmd.Execute(); err == nil || !strings.HasPrefix(err.Error(), "config:") {
+			t.Errorf("%s: expected a config error, got %v", action, err)
+		}
+		if w, _ := state.Read(dir, "603"); w.Status != state.StatusPending {
+			t.Errorf("%s: expected the worker left pending, got %s", action, w.Status)
+		}
+	}
+}
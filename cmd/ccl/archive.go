@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive <id>...",
+	Short: "Move finished workers into the archive",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runArchive,
+}
+
+func init() {
+	rootCmd.AddCommand(archiveCmd)
+}
+
+func runArchive(cmd *cobra.Command, args []string) error {
+	for _, ref := range args {
+		id, err := state.Resolve(stateDir, ref)
+		if err != nil {
+			return err
+		}
+		if _, err := worker.Archive(stateDir, id, state.FinishedStatuses); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Archived worker %s\n", id)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestCleanArchivesByDefault(t *testing.T) {
+	resetListFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	cleanAll, cleanPurge = false, false
+	state.Write(dir, &state.Worker{ID: "920", Status: state.StatusDone, Directory: "/tmp", Task: "archive me"})
+	writeTestLog(t, dir, "920")
+
+	rootCmd.SetArgs([]string{"clean"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("clean: %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"list", "--archived", "--json"})
+	buf.Reset()
+	rootCmd.Execute()
+	resetListFlags()
+	var workers []map[string]interface{}
+	json.Unmarshal([]byte(buf.String()), &workers)
+	if len(workers) != 1 || workers[0]["archived"] != true {
+		t.Fatalf("expected archived worker in list: %s", buf.String())
+	}
+
+	logsJSON, logsFollow = false, false
+	rootCmd.SetArgs([]string{"logs", "920"})
+	buf.Reset()
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("logs: %v", err)
+	}
+	if !strings.Contains(buf.String(), "I found the bug") {
+		t.Errorf("expected archived log to render: %s", buf.String())
+	}
+}
+
+func TestCleanPurge(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	state.Write(dir, &state.Worker{ID: "921", Status: state.StatusError, Directory: "/tmp", Task: "purge me"})
+
+	rootCmd.SetArgs([]string{"clean", "--purge"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.Execute()
+	cleanPurge = false
+
+	if _, err := state.ReadAny(dir, "921"); err == nil {
+		t.Error("purged worker should not be archived")
+	}
+	if _, err := os.Stat(filepath.Join(dir, state.ArchiveDirName)); !os.IsNotExist(err) {
+		t.Error("purge should not create an archive")
+	}
+}
+
+func TestArchiveCommand(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	state.Write(dir, &state.Worker{ID: "922", Status: state.StatusKilled, Directory: "/tmp", Task: "k"})
+	state.Write(dir, &state.Worker{ID: "923", Status: state.StatusWorking, Directory: "/tmp", Task: "w", PID: 1})
+
+	rootCmd.SetArgs([]string{"archive", "922"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	rootCmd.SetArgs([]string{"archive", "923"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected error archiving a working worker")
+	}
+}
@@ -4,19 +4,24 @@ import (
 	"fmt"
 
 	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
 	"github.com/spf13/cobra"
 )
 
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
-	Short: "Remove done/error workers",
+	Short: "Archive finished (done/error/killed/denied/timeout) workers",
 	RunE:  runClean,
 }
 
-var cleanAll bool
+var (
+	cleanAll   bool
+	cleanPurge bool
+)
 
 func init() {
-	cleanCmd.Flags().BoolVar(&cleanAll, "all", false, "Remove all workers including working")
+	cleanCmd.Flags().BoolVar(&cleanAll, "all", false, "Include all workers, even working ones")
+	cleanCmd.Flags().BoolVar(&cleanPurge, "purge", false, "Delete permanently instead of archiving")
 	rootCmd.AddCommand(cleanCmd)
 }
 
@@ -26,14 +31,29 @@ func runClean(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	var from []state.Status
+	if !cleanAll {
+		from = state.FinishedStatuses
+	}
+
 	removed := 0
 	for _, w := range workers {
-		if cleanAll || w.Status == state.StatusDone || w.Status == state.StatusError {
-			state.Delete(stateDir, w.ID)
+		if cleanPurge {
+			_, err = worker.Remove(stateDir, w.ID, from)
+		} else {
+			_, err = worker.Archive(stateDir, w.ID, from)
+		}
+		if err == nil {
 			removed++
+		} else if w.WorktreePath != "" && w.Status.In(from) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Skipped %v\n", err)
 		}
 	}
 
-	fmt.Fprintf(cmd.OutOrStdout(), "Cleaned %d worker(s).\n", removed)
+	if cleanPurge {
+		fmt.Fprintf(cmd.OutOrStdout(), "Purged %d worker(s).\n", removed)
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Archived %d worker(s).\n", removed)
+	}
 	return nil
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BurntSushi/toml"
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective config and where each value comes from",
+	Long: `Print the effective config and where each value comes from.
+
+With --dir, the .ccl.toml files from that directory up to its git root are
+merged over the user config, the closest one winning, as they are for
+workers created there.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+var configShowDir string
+
+func init() {
+	configShowCmd.Flags().StringVar(&configShowDir, "dir", "", "Project directory whose .ccl.toml files to merge")
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadFor(configPath, configShowDir)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	// Round-trip through TOML to get the values as they'd be written.
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(cfg); err != nil {
+		return err
+	}
+	var tree map[string]any
+	if _, err := toml.Decode(buf.String(), &tree); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	for _, kv := range flatten(nil, tree) {
+		src := cfg.Source(kv.key)
+		if src == "" {
+			src = "default"
+		}
+		fmt.Fprintf(tw, "%s = %s\t# %s\n", kv.key, kv.value, src)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	warnIgnored(cmd, cfg)
+	return nil
+}
+
+// warnIgnored tells the user about the keys cfg's project files set that
+// only the user config may.
+func warnIgnored(cmd *cobra.Command, cfg *config.Config) {
+	for _, msg := range cfg.Ignored() {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s; ignored\n", msg)
+	}
+}
+
+type keyValue struct {
+	key   string
+	value string
+}
+
+// flatten lists the leaf values of a decoded TOML tree by dotted key, in
+// key order, each value formatted as TOML.
+func flatten(prefix toml.Key, tree map[string]any) []keyValue {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []keyValue
+	for _, name := range names {
+		key := append(prefix[:len(prefix):len(prefix)], name)
+		if sub, ok := tree[name].(map[string]any); ok {
+			out = append(out, flatten(key, sub)...)
+			continue
+		}
+		var buf bytes.Buffer
+		toml.NewEncoder(&buf).Encode(map[string]any{"v": tree[name]})
+		value := strings.TrimSpace(strings.TrimPrefix(buf.String(), "v = "))
+		out = append(out, keyValue{key.String(), value})
+	}
+	return out
+}
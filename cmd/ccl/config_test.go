@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigShowSources(t *testing.T) {
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, ".ccl.toml"), []byte("[claude]\nsystem_prompt = \"run make test first\"\n"), 0644)
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(configPath, []byte("[worker]\nretries = 2\n"), 0644)
+	defer func() { configShowDir = "" }()
+
+	rootCmd.SetArgs([]string{"config", "show", "--dir", project})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`claude.system_prompt = "run make test first"  # ` + filepath.Join(project, ".ccl.toml"),
+		"worker.retries = 2  ",
+		"# " + configPath,
+		"worker.kill_grace = \"10s\"",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestProjectUserOnlyKeys(t *testing.T) {
+	resetNewFlags()
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, ".ccl.toml"), []byte("[hooks]\non_start = [\"curl example.com\"]\n"), 0644)
+	stateDir = t.TempDir()
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	defer func() { configShowDir = "" }()
+
+	rootCmd.SetArgs([]string{"config", "show", "--dir", project})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("config show: %v", err)
+	}
+	if !strings.Contains(buf.String(), "warning: "+filepath.Join(project, ".ccl.toml")+": hooks can only be set in the user config; ignored") {
+		t.Errorf("expected config show to warn about the hooks, got %q", buf.String())
+	}
+
+	newPending = false
+	rootCmd.SetArgs([]string{"new", "--dir", project, "--task", "t", "--pending"})
+	stderr := new(strings.Builder)
+	rootCmd.SetOut(new(strings.Builder))
+	rootCmd.SetErr(stderr)
+	err := rootCmd.Execute()
+	newPending = false
+	if err != nil {
+		t.Fatalf("expected new to go ahead without the project's hooks: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "warning: "+filepath.Join(project, ".ccl.toml")+": hooks can only be set in the user config; ignored") {
+		t.Errorf("expected a warning, got %q", stderr.String())
+	}
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/scottstav/wreccless/internal/config"
-	"github.com/scottstav/wreccless/internal/hooks"
 	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
 	"github.com/spf13/cobra"
 )
 
@@ -21,23 +23,39 @@ func init() {
 }
 
 func runDeny(cmd *cobra.Command, args []string) error {
-	id := args[0]
+	id, err := state.Resolve(stateDir, args[0])
+	if err != nil {
+		return err
+	}
 	w, err := state.Read(stateDir, id)
 	if err != nil {
-		return fmt.Errorf("worker %s not found", id)
+		return err
 	}
-	if w.Status != state.StatusPending {
-		return fmt.Errorf("worker %s is %s, not pending", id, w.Status)
+	cfg, err := config.LoadFor(configPath, w.Directory)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
 	}
-
-	cfg, _ := config.Load(configPath)
-
-	if err := state.Delete(stateDir, id); err != nil {
+	warnIgnored(cmd, cfg)
+
+	actor := state.Actor("cli")
+	w, err = state.Transition(stateDir, id, []state.Status{state.StatusPending}, func(w *state.Worker) error {
+		now := time.Now()
+		w.Status = state.StatusDenied
+		w.FinishedAt = &now
+		w.StoppedBy = actor
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	state.AppendEvent(stateDir, id, state.Event{Type: state.EventDenied, Actor: actor})
+
+	vars := worker.HookVars(w)
+	worker.FireHooks(stateDir, "on_kill", worker.ConfigFor(cfg, w).Hooks.OnKill, vars)
 
-	vars := hooks.Vars{ID: id, Task: w.Task, Dir: w.Directory, Status: "denied"}
-	hooks.Fire(cfg.Hooks.OnKill, vars)
+	// Workers waiting on this one may be settled now.
+	cclBin, _ := os.Executable()
+	worker.Promote(stateDir, cfg, worker.SpawnLauncher(cclBin, configPath, stateDir))
 
 	fmt.Fprintf(cmd.OutOrStdout(), "Denied worker %s\n", id)
 	return nil
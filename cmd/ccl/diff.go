@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/scottstav/wreccless/internal/git"
+	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <id>",
+	Short: "Show the changes a worker made",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDiff,
+}
+
+var (
+	diffStat  bool
+	diffPatch bool
+)
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffStat, "stat", false, "Show a diffstat instead of the full diff")
+	diffCmd.Flags().BoolVar(&diffPatch, "patch", false, "Export the worker's commits in git format-patch form")
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if diffStat && diffPatch {
+		return fmt.Errorf("--stat and --patch are mutually exclusive")
+	}
+	id, err := state.ResolveAny(stateDir, args[0])
+	if err != nil {
+		return err
+	}
+	w, err := state.ReadAny(stateDir, id)
+	if err != nil {
+		return fmt.Errorf("worker %s not found", id)
+	}
+	info := w.Git
+	if info == nil {
+		return fmt.Errorf("worker %s has no git information (not a repository, or not started)", id)
+	}
+
+	// A finished worker's changes are pinned by commits and readable from
+	// any checkout of the repository; a running one is compared against
+	// its live working tree.
+	dir := w.Directory
+	if info.End == "" {
+		dir = worker.WorkDir(w)
+	}
+
+	var out string
+	if diffPatch {
+		end := info.EndHead
+		if end == "" {
+			end = "HEAD"
+		}
+		if len(info.Commits) == 0 && info.EndHead != "" {
+			return fmt.Errorf("worker %s made no commits; use ccl diff %s for its uncommitted changes", id, id)
+		}
+		out, err = git.FormatPatch(dir, info.StartHead, end)
+	} else {
+		out, err = git.Diff(dir, info.Base, info.End, diffStat)
+	}
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		fmt.Fprintln(cmd.OutOrStdout(), out)
+	}
+	return nil
+}
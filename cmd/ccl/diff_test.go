@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	script := filepath.Join(t.TempDir(), "claude")
+	os.WriteFile(script, []byte(`#!/bin/sh
+echo hello > greeting.txt
+git add greeting.txt
+git -c user.name=t -c user.email=t@t commit -q -m "Add greeting"
+`), 0755)
+	state.Write(dir, &state.Worker{ID: "900", Status: state.StatusWorking, Directory: repo, Task: "greet", SessionID: "s"})
+	if err := worker.Run(dir, "900", config.Defaults(), script); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		diffStat, diffPatch, statusJSON = false, false, false
+		rootCmd.SetArgs(args)
+		buf := new(strings.Builder)
+		rootCmd.SetOut(buf)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return buf.String()
+	}
+
+	if out := run("diff", "900"); !strings.Contains(out, "+hello") {
+		t.Errorf("diff missing change:\n%s", out)
+	}
+	if out := run("diff", "900", "--stat"); !strings.Contains(out, "greeting.txt | 1 +") {
+		t.Errorf("unexpected stat:\n%s", out)
+	}
+	if out := run("diff", "900", "--patch"); !strings.Contains(out, "Subject: [PATCH] Add greeting") {
+		t.Errorf("unexpected patch:\n%s", out)
+	}
+	if out := run("status", "900"); !strings.Contains(out, "Changes:    1 file(s), +1 -0") || !strings.Contains(out, "Add greeting") {
+		t.Errorf("status missing changes:\n%s", out)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Apply the [retention] policy to the state directory",
+	RunE:  runGC,
+}
+
+var gcDryRun bool
+
+func init() {
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "List what would be removed without deleting")
+	rootCmd.AddCommand(gcCmd)
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	removals, err := worker.GC(stateDir, cfg, gcDryRun)
+	if err != nil {
+		return err
+	}
+	if len(removals) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Nothing to remove.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tREASON")
+	for _, r := range removals {
+		status := string(r.Worker.Status)
+		if r.Worker.Archived {
+			status += " (archived)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Worker.ID, status, r.Reason)
+	}
+	tw.Flush()
+
+	if gcDryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "Would remove %d worker(s).\n", len(removals))
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed %d worker(s).\n", len(removals))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestGCDryRun(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(configPath, []byte("[retention.max_age]\ndone = \"1d\"\n"), 0644)
+	old := time.Now().Add(-48 * time.Hour)
+	state.Write(dir, &state.Worker{ID: "1400", Status: state.StatusDone, Directory: "/tmp", Task: "old", FinishedAt: &old})
+
+	rootCmd.SetArgs([]string{"gc", "--dry-run"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	if !strings.Contains(buf.String(), "1400") || !strings.Contains(buf.String(), "max age") {
+		t.Errorf("expected removal with reason: %s", buf.String())
+	}
+	if _, err := state.Read(dir, "1400"); err != nil {
+		t.Error("dry run should not delete")
+	}
+
+	rootCmd.SetArgs([]string{"gc"})
+	gcDryRun = false
+	buf.Reset()
+	rootCmd.Execute()
+	if _, err := state.Read(dir, "1400"); err == nil {
+		t.Error("gc should delete expired worker")
+	}
+}
+
+func TestGCRejectsUnknownMaxAgeStatus(t *testing.T) {
+	stateDir = t.TempDir()
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(configPath, []byte("[retention.max_age]\nfailed = \"1d\"\n"), 0644)
+
+	rootCmd.SetArgs([]string{"gc", "--dry-run"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	err := rootCmd.Execute()
+	gcDryRun = false
+	if err == nil || !strings.Contains(err.Error(), `unknown status "failed"`) {
+		t.Errorf("expected the typo to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/scottstav/wreccless/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <id>",
+	Short: "Show the transition journal of a worker",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistory,
+}
+
+var historyJSON bool
+
+func init() {
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Output JSON")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	id, err := state.ResolveAny(stateDir, args[0])
+	if err != nil {
+		return err
+	}
+	events, err := state.ReadEvents(stateDir, id)
+	if err != nil {
+		return err
+	}
+
+	if historyJSON {
+		if events == nil {
+			events = []state.Event{}
+		}
+		data, err := json.Marshal(events)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return nil
+	}
+
+	if len(events) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "No history for worker %s.\n", id)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tEVENT\tDETAILS")
+	for _, e := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Time.Format("2006-01-02 15:04:05"), e.Type, e.Summary())
+	}
+	tw.Flush()
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestHistoryRecordsLifecycle(t *testing.T) {
+	resetNewFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
+	historyJSON = false
+
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "journal me", "--pending"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	id := strings.TrimSpace(buf.String())
+
+	rootCmd.SetArgs([]string{"history", id})
+	buf.Reset()
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if !strings.Contains(buf.String(), "created") || !strings.Contains(buf.String(), "status=pending") {
+		t.Errorf("expected created event: %s", buf.String())
+	}
+}
+
+func TestHistoryJSON(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	state.Write(dir, &state.Worker{ID: "1300", Status: state.StatusDone, Directory: "/tmp", Task: "t"})
+	code := 0
+	state.AppendEvent(dir, "1300", state.Event{Type: state.EventCreated, Status: state.StatusWorking})
+	state.AppendEvent(dir, "1300", state.Event{Type: state.EventFinished, Status: state.StatusDone, ExitCode: &code})
+
+	rootCmd.SetArgs([]string{"history", "1300", "--json"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	historyJSON = false
+
+	var events []map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &events); err != nil {
+		t.Fatalf("json: %v (%s)", err, buf.String())
+	}
+	if len(events) != 2 || events[1]["type"] != "finished" || events[1]["exit_code"] != float64(0) {
+		t.Errorf("unexpected events: %v", events)
+	}
+}
@@ -2,43 +2,115 @@ package main
 
 import (
 	"fmt"
-	"syscall"
+	"os"
+	"sync"
 
 	"github.com/scottstav/wreccless/internal/config"
-	"github.com/scottstav/wreccless/internal/hooks"
 	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
 	"github.com/spf13/cobra"
 )
 
 var killCmd = &cobra.Command{
-	Use:   "kill <id>",
-	Short: "Kill a running worker",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runKill,
+	Use:   "kill [id]",
+	Short: "Kill a running or queued worker",
+	Long: `Kill a running or queued worker.
+
+A running worker's supervisor and claude's process group get SIGTERM, then
+SIGKILL if they are still alive after [worker] kill_grace. The worker is
+marked killed once they are gone.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runKill,
 }
 
+var (
+	killForce bool
+	killAll   bool
+)
+
 func init() {
+	killCmd.Flags().BoolVar(&killForce, "force", false, "Send SIGKILL straight away")
+	killCmd.Flags().BoolVar(&killAll, "all", false, "Kill every running, paused, queued and blocked worker")
 	rootCmd.AddCommand(killCmd)
 }
 
 func runKill(cmd *cobra.Command, args []string) error {
-	id := args[0]
-	w, err := state.Read(stateDir, id)
-	if err != nil {
-		return fmt.Errorf("worker %s not found", id)
+	if killAll == (len(args) == 1) {
+		return fmt.Errorf("give a worker id or --all")
 	}
+	var ids []string
+	if killAll {
+		workers, err := state.List(stateDir)
+		if err != nil {
+			return err
+		}
+		for _, w := range workers {
+			switch w.Status {
+			case state.StatusWorking, state.StatusPaused, state.StatusQueued, state.StatusBlocked:
+				ids = append(ids, w.ID)
+			}
+		}
+	} else {
+		id, err := state.Resolve(stateDir, args[0])
+		if err != nil {
+			return err
+		}
+		ids = []string{id}
+	}
+	// From here on errors are about the workers, not how ccl was invoked.
+	cmd.SilenceUsage = true
 
-	cfg, _ := config.Load(configPath)
-
-	if w.PID > 0 {
-		syscall.Kill(w.PID, syscall.SIGTERM)
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
 	}
+	actor := state.Actor("cli")
 
-	state.Delete(stateDir, id)
+	// Stop them all at once so --all waits out one grace period, not one each.
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w, err := state.Read(stateDir, id)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			// kill_grace may be set per project.
+			wcfg := worker.ConfigFor(cfg, w)
+			w, err = worker.Kill(stateDir, id, actor, wcfg.Worker.KillGrace.Duration, killForce)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			worker.FireHooks(stateDir, "on_kill", wcfg.Hooks.OnKill, worker.HookVars(w))
+		}()
+	}
+	wg.Wait()
 
-	vars := hooks.Vars{ID: id, Task: w.Task, Dir: w.Directory, Status: "killed"}
-	hooks.Fire(cfg.Hooks.OnKill, vars)
+	// Workers waiting on the killed ones may be settled now.
+	cclBin, _ := os.Executable()
+	worker.Promote(stateDir, cfg, worker.SpawnLauncher(cclBin, configPath, stateDir))
 
-	fmt.Fprintf(cmd.OutOrStdout(), "Killed worker %s\n", id)
+	var failed error
+	for i, id := range ids {
+		if errs[i] != nil {
+			// A lone failure is returned and printed by main.
+			if len(ids) > 1 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", errs[i])
+			}
+			failed = errs[i]
+			continue
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Killed worker %s\n", id)
+	}
+	if len(ids) == 1 {
+		return failed
+	}
+	if failed != nil {
+		return fmt.Errorf("some workers could not be killed")
+	}
 	return nil
 }
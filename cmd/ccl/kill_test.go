@@ -1,9 +1,13 @@
 package main
 
 import (
+	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/scottstav/wreccless/internal/state"
 )
@@ -20,18 +24,73 @@ func TestKill(t *testing.T) {
 	rootCmd.SetOut(buf)
 	rootCmd.Execute()
 
-	_, err := state.Read(dir, "800")
-	if err == nil {
-		t.Error("worker should be deleted after kill")
+	w, err := state.Read(dir, "800")
+	if err != nil {
+		t.Fatalf("worker should be kept after kill: %v", err)
+	}
+	if w.Status != state.StatusKilled {
+		t.Errorf("expected killed, got %s", w.Status)
+	}
+	if w.StoppedBy == "" || w.KillSignal != "SIGTERM" || w.FinishedAt == nil {
+		t.Errorf("expected who/when/signal to be recorded: %+v", w)
+	}
+}
+
+func TestKillKeepsLog(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
+	state.Write(dir, &state.Worker{ID: "801", Status: state.StatusWorking, Directory: "/tmp", Task: "kill me", PID: 99999})
+	logPath := filepath.Join(dir, "801.log")
+	os.WriteFile(logPath, []byte("{\"type\":\"assistant\",\"content\":\"halfway\"}\n"), 0644)
+
+	rootCmd.SetArgs([]string{"kill", "801"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.Execute()
+
+	if _, err := os.Stat(logPath); err != nil {
+		t.Errorf("log should survive kill: %v", err)
+	}
+}
+
+func TestKillAll(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
+	state.Write(dir, &state.Worker{ID: "802", Status: state.StatusWorking, Directory: "/tmp", Task: "a", PID: 99999})
+	state.Write(dir, &state.Worker{ID: "803", Status: state.StatusQueued, Directory: "/tmp", Task: "b"})
+	state.Write(dir, &state.Worker{ID: "804", Status: state.StatusDone, Directory: "/tmp", Task: "c"})
+	state.Write(dir, &state.Worker{ID: "805", Status: state.StatusPaused, Directory: "/tmp", Task: "d", PID: 99999})
+
+	rootCmd.SetArgs([]string{"kill", "--all"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	err := rootCmd.Execute()
+	killAll = false
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	for id, want := range map[string]state.Status{"802": state.StatusKilled, "803": state.StatusKilled, "804": state.StatusDone, "805": state.StatusKilled} {
+		if w, _ := state.Read(dir, id); w.Status != want {
+			t.Errorf("worker %s: expected %s, got %s", id, want, w.Status)
+		}
+	}
+	if w, _ := state.Read(dir, "803"); w.KillSignal != "" {
+		t.Errorf("queued worker had nothing to signal, got %q", w.KillSignal)
 	}
 }
 
 func TestClean(t *testing.T) {
 	dir := t.TempDir()
 	stateDir = dir
+	cleanAll, cleanPurge = false, false
 	state.Write(dir, &state.Worker{ID: "900", Status: state.StatusDone, Directory: "/tmp", Task: "done"})
 	state.Write(dir, &state.Worker{ID: "901", Status: state.StatusError, Directory: "/tmp", Task: "error"})
 	state.Write(dir, &state.Worker{ID: "902", Status: state.StatusWorking, Directory: "/tmp", Task: "working", PID: 1})
+	state.Write(dir, &state.Worker{ID: "903", Status: state.StatusKilled, Directory: "/tmp", Task: "killed"})
+	state.Write(dir, &state.Worker{ID: "904", Status: state.StatusDenied, Directory: "/tmp", Task: "denied"})
 
 	rootCmd.SetArgs([]string{"clean"})
 	buf := new(strings.Builder)
@@ -53,6 +112,7 @@ func TestCleanAll(t *testing.T) {
 	state.Write(dir, &state.Worker{ID: "910", Status: state.StatusDone, Directory: "/tmp", Task: "done"})
 	state.Write(dir, &state.Worker{ID: "911", Status: state.StatusWorking, Directory: "/tmp", Task: "working", PID: 1})
 
+	cleanPurge = false
 	rootCmd.SetArgs([]string{"clean", "--all"})
 	buf := new(strings.Builder)
 	rootCmd.SetOut(buf)
@@ -63,3 +123,37 @@ func TestCleanAll(t *testing.T) {
 		t.Errorf("expected 0 remaining, got %d", len(workers))
 	}
 }
+
+func TestKillProjectGrace(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, ".ccl.toml"), []byte("[worker]\nkill_grace = \"100ms\"\n"), 0644)
+
+	// A process group that ignores SIGTERM, so only SIGKILL ends it.
+	proc := exec.Command("sh", "-c", "trap '' TERM; while :; do sleep 0.1; done")
+	proc.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := proc.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	go proc.Wait()
+	pid := proc.Process.Pid
+	t.Cleanup(func() { syscall.Kill(-pid, syscall.SIGKILL) })
+	time.Sleep(100 * time.Millisecond)
+	state.Write(dir, &state.Worker{ID: "806", Status: state.StatusWorking, Directory: project, Task: "t", PID: pid, PGID: pid})
+
+	rootCmd.SetArgs([]string{"kill", "806"})
+	rootCmd.SetOut(new(strings.Builder))
+	rootCmd.SetErr(new(strings.Builder))
+	start := time.Now()
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("kill waited out the user config's grace, not the project's")
+	}
+	if w, _ := state.Read(dir, "806"); w.KillSignal != "SIGKILL" {
+		t.Errorf("expected escalation after the project's grace, got %+v", w)
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
-	"syscall"
 	"text/tabwriter"
 
 	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
 	"github.com/spf13/cobra"
 )
 
@@ -19,35 +20,34 @@ var listCmd = &cobra.Command{
 }
 
 var (
-	listJSON   bool
-	listStatus string
+	listJSON     bool
+	listStatus   string
+	listArchived bool
+	listLabel    string
 )
 
 func init() {
 	listCmd.Flags().BoolVar(&listJSON, "json", false, "Output JSON")
-	listCmd.Flags().StringVar(&listStatus, "status", "", "Filter by status (pending|working|done|error)")
+	listCmd.Flags().StringVar(&listStatus, "status", "", "Filter by status (pending|queued|blocked|working|paused|done|error|timeout|killed|denied)")
+	listCmd.Flags().BoolVar(&listArchived, "archived", false, "Include archived workers")
+	listCmd.Flags().StringVar(&listLabel, "label", "", "Only workers with this label")
 	rootCmd.AddCommand(listCmd)
 }
 
-func isProcessAlive(pid int) bool {
-	if pid <= 0 {
-		return false
-	}
-	return syscall.Kill(pid, 0) == nil
-}
-
 func runList(cmd *cobra.Command, args []string) error {
 	workers, err := state.List(stateDir)
 	if err != nil {
 		return err
 	}
 
-	// Stale detection
-	for _, w := range workers {
-		if w.Status == state.StatusWorking && w.PID > 0 && !isProcessAlive(w.PID) {
-			w.Status = state.StatusError
-			state.Write(stateDir, w)
+	worker.MarkStale(stateDir, workers)
+
+	if listArchived {
+		archived, err := state.ListArchived(stateDir)
+		if err != nil {
+			return err
 		}
+		workers = append(archived, workers...)
 	}
 
 	if listStatus != "" {
@@ -59,6 +59,15 @@ func runList(cmd *cobra.Command, args []string) error {
 		}
 		workers = filtered
 	}
+	if listLabel != "" {
+		var filtered []*state.Worker
+		for _, w := range workers {
+			if slices.Contains(w.Labels, listLabel) {
+				filtered = append(filtered, w)
+			}
+		}
+		workers = filtered
+	}
 
 	if len(workers) == 0 {
 		fmt.Fprintln(cmd.OutOrStdout(), "No workers.")
@@ -75,7 +84,7 @@ func runList(cmd *cobra.Command, args []string) error {
 	}
 
 	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
-	fmt.Fprintln(tw, "ID\tSTATUS\tDIRECTORY\tTASK")
+	fmt.Fprintln(tw, "ID\tSTATUS\tCOST\tTURNS\tDIRECTORY\tTASK")
 	home, _ := os.UserHomeDir()
 	for _, w := range workers {
 		dir := w.Directory
@@ -86,7 +95,16 @@ func runList(cmd *cobra.Command, args []string) error {
 		if len(task) > 60 {
 			task = task[:57] + "..."
 		}
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", w.ID, w.Status, dir, task)
+		status := string(w.Status)
+		if w.Archived {
+			status += " (archived)"
+		}
+		cost, turns := "-", "-"
+		if w.NumTurns > 0 || w.CostUSD > 0 {
+			cost = fmt.Sprintf("$%.2f", w.CostUSD)
+			turns = fmt.Sprint(w.NumTurns)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", w.ID, status, cost, turns, dir, task)
 	}
 	tw.Flush()
 	return nil
@@ -125,3 +125,24 @@ func TestListStaleDetection(t *testing.T) {
 		t.Errorf("stale worker should be error, got %s", workers[0]["status"])
 	}
 }
+
+func TestListCostColumns(t *testing.T) {
+	resetListFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	state.Write(dir, &state.Worker{ID: "400", Status: state.StatusDone, Directory: "/tmp/d", Task: "costly", CostUSD: 1.234, NumTurns: 12})
+
+	rootCmd.SetArgs([]string{"list"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "COST") || !strings.Contains(out, "TURNS") {
+		t.Errorf("missing columns: %s", out)
+	}
+	if !strings.Contains(out, "$1.23") || !strings.Contains(out, "12") {
+		t.Errorf("missing metrics: %s", out)
+	}
+}
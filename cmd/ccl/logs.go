@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/scottstav/wreccless/internal/logrender"
+	"github.com/scottstav/wreccless/internal/state"
 	"github.com/spf13/cobra"
 )
 
@@ -31,10 +32,32 @@ func init() {
 }
 
 func runLogs(cmd *cobra.Command, args []string) error {
-	id := args[0]
+	id, err := state.ResolveAny(stateDir, args[0])
+	if err != nil {
+		return err
+	}
 	logPath := filepath.Join(stateDir, id+".log")
+	var parse func([]byte) []logrender.Event
+	if w, err := state.ReadAny(stateDir, id); err == nil {
+		parse = logrender.Parser(w.LogFormat)
+	} else {
+		parse = logrender.ParseLine
+	}
 
 	f, err := os.Open(logPath)
+	if os.IsNotExist(err) {
+		// Archived logs are complete, so there is nothing to follow.
+		r, err := state.OpenLog(stateDir, id)
+		if err != nil {
+			return fmt.Errorf("no log file for worker %s", id)
+		}
+		defer r.Close()
+		if logsJSON {
+			_, err := io.Copy(cmd.OutOrStdout(), r)
+			return err
+		}
+		return renderHuman(cmd.OutOrStdout(), r, parse)
+	}
 	if err != nil {
 		return fmt.Errorf("no log file for worker %s", id)
 	}
@@ -49,21 +72,21 @@ func runLogs(cmd *cobra.Command, args []string) error {
 	}
 
 	if logsFollow {
-		return tailFileHuman(cmd.OutOrStdout(), f)
+		return tailFileHuman(cmd.OutOrStdout(), f, parse)
 	}
-	return renderHuman(cmd.OutOrStdout(), f)
+	return renderHuman(cmd.OutOrStdout(), f, parse)
 }
 
-func renderHuman(out io.Writer, r io.Reader) error {
+func renderHuman(out io.Writer, r io.Reader, parse func([]byte) []logrender.Event) error {
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		renderLine(out, scanner.Bytes())
+		renderLine(out, scanner.Bytes(), parse)
 	}
 	return scanner.Err()
 }
 
-func renderLine(out io.Writer, line []byte) {
-	events := logrender.ParseLine(line)
+func renderLine(out io.Writer, line []byte, parse func([]byte) []logrender.Event) {
+	events := parse(line)
 	text := logrender.RenderPlain(events)
 	if text != "" {
 		io.WriteString(out, text)
@@ -80,14 +103,14 @@ func tailFile(out io.Writer, f *os.File) error {
 	}
 }
 
-func tailFileHuman(out io.Writer, f *os.File) error {
+func tailFileHuman(out io.Writer, f *os.File, parse func([]byte) []logrender.Event) error {
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
-		renderLine(out, scanner.Bytes())
+		renderLine(out, scanner.Bytes(), parse)
 	}
 	for {
 		if scanner.Scan() {
-			renderLine(out, scanner.Bytes())
+			renderLine(out, scanner.Bytes(), parse)
 		} else {
 			time.Sleep(200 * time.Millisecond)
 			scanner = bufio.NewScanner(f)
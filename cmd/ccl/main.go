@@ -16,6 +16,8 @@ var (
 var rootCmd = &cobra.Command{
 	Use:   "ccl",
 	Short: "Claude Code Launcher — manage background Claude workers",
+	// main prints the error, once.
+	SilenceErrors: true,
 }
 
 func init() {
@@ -29,7 +31,7 @@ func init() {
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/scottstav/wreccless/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade all state files to the current schema",
+	RunE:  runMigrate,
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	migrated, err := state.Migrate(stateDir)
+	for _, id := range migrated {
+		fmt.Fprintln(cmd.OutOrStdout(), id)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Migrated %d worker(s) to schema %d.\n", len(migrated), state.SchemaVersion)
+	return nil
+}
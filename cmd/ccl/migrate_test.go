@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	legacy := `{"id":"1740700001","status":"done","directory":"/tmp","task":"legacy","session_id":"aaaa0001-0000-0000-0000-000000000001","finished_at":"2025-02-28T00:00:00Z"}`
+	os.WriteFile(filepath.Join(dir, "1740700001.json"), []byte(legacy), 0644)
+
+	rootCmd.SetArgs([]string{"migrate"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Migrated 1 worker(s)") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "1740700001.json"))
+	if !strings.Contains(string(data), `"schema_version": 2`) {
+		t.Errorf("file not rewritten: %s", data)
+	}
+
+	buf.Reset()
+	rootCmd.Execute()
+	if !strings.Contains(buf.String(), "Migrated 0 worker(s)") {
+		t.Errorf("second run should be a no-op: %s", buf.String())
+	}
+}
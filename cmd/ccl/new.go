@@ -3,14 +3,18 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/scottstav/wreccless/internal/config"
-	"github.com/scottstav/wreccless/internal/hooks"
+	"github.com/scottstav/wreccless/internal/editor"
 	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/templates"
 	"github.com/scottstav/wreccless/internal/worker"
 	"github.com/spf13/cobra"
 )
@@ -22,65 +26,300 @@ var newCmd = &cobra.Command{
 }
 
 var (
-	newDir     string
-	newTask    string
-	newImage   string
-	newPending bool
-	newJSON    bool
+	newDir      string
+	newTask     string
+	newTaskFile string
+	newEdit     bool
+	newImage    string
+	newAttach   []string
+	newContext  []string
+	newPending  bool
+	newPriority int
+	newWorktree bool
+	newTimeout  config.Duration
+	newRetries  int
+	newRunner   string
+	newProfile  string
+	newEnv      []string
+	newEnvFiles []string
+	newTemplate string
+	newVars     []string
+	newLabels   []string
+	newAfter    []string
+	newAfterAny bool
+	newOnFail   bool
+	newJSON     bool
 )
 
 func init() {
-	newCmd.Flags().StringVar(&newDir, "dir", "", "Project directory (required)")
-	newCmd.Flags().StringVar(&newTask, "task", "", "Task description (required)")
+	newCmd.Flags().StringVar(&newDir, "dir", "", "Project directory (required unless the template sets one)")
+	newCmd.Flags().StringVar(&newTask, "task", "", "Task description")
+	newCmd.Flags().StringVar(&newTaskFile, "task-file", "", "Read the task from a file, or stdin if -")
+	newCmd.Flags().BoolVar(&newEdit, "edit", false, "Write the task in $EDITOR, starting from --task or --task-file if given")
+	newCmd.Flags().StringArrayVar(&newAttach, "attach", nil, "File for the agent to read: image, PDF, text (repeatable)")
+	newCmd.Flags().StringArrayVar(&newContext, "context", nil, "File or glob whose contents go in the prompt (repeatable)")
 	newCmd.Flags().StringVar(&newImage, "image", "", "Image path for claude to reference")
+	newCmd.Flags().MarkDeprecated("image", "use --attach")
+	newCmd.MarkFlagFilename("attach")
+	newCmd.MarkFlagFilename("context")
+	newCmd.MarkFlagFilename("task-file")
+	newCmd.MarkFlagFilename("env-file")
 	newCmd.Flags().BoolVar(&newPending, "pending", false, "Create as pending (require manual approval)")
+	newCmd.Flags().IntVar(&newPriority, "priority", 0, "Queue priority; higher starts first when slots are full")
+	newCmd.Flags().BoolVar(&newWorktree, "worktree", false, "Run in a git worktree on branch ccl/<id> (overrides [worker] isolation)")
+	newCmd.Flags().Var(&newTimeout, "timeout", "Stop the worker after this long, e.g. 30m (default [worker] default_timeout)")
+	newCmd.Flags().IntVar(&newRetries, "retries", 0, "Resume the session up to N more times after a failure (default [worker] retries)")
+	newCmd.Flags().StringVar(&newRunner, "runner", "", "Agent to run the task with: claude (default) or a [runners.<name>] from config")
+	newCmd.Flags().StringVar(&newProfile, "profile", "", "Apply [profiles.<name>] from config to this worker")
+	newCmd.Flags().StringArrayVar(&newEnv, "env", nil, "Set KEY=VALUE in the agent's environment (repeatable; stored with the worker)")
+	newCmd.Flags().StringArrayVar(&newEnvFiles, "env-file", nil, "Read KEY=VALUE lines from a file when the worker starts (repeatable; for secrets, only the path is stored)")
+	newCmd.Flags().StringVar(&newTemplate, "template", "", "Create from a task template in the templates directory (see ccl templates)")
+	newCmd.Flags().StringArrayVar(&newVars, "var", nil, "Set a template variable, NAME=VALUE (repeatable)")
+	newCmd.Flags().StringArrayVar(&newLabels, "label", nil, "Label the worker (repeatable; added to the template's)")
+	newCmd.Flags().StringSliceVar(&newAfter, "after", nil, "Wait, blocked, until these workers finish done (ids or prefixes, comma-separated or repeated)")
+	newCmd.Flags().BoolVar(&newAfterAny, "after-any", false, "With --after, wait for any one of the workers instead of all")
+	newCmd.Flags().BoolVar(&newOnFail, "on-failure", false, "With --after, wait for the workers to fail instead of succeed")
 	newCmd.Flags().BoolVar(&newJSON, "json", false, "Output JSON")
-	newCmd.MarkFlagRequired("dir")
-	newCmd.MarkFlagRequired("task")
 	rootCmd.AddCommand(newCmd)
 }
 
+// readTask returns the task given by --task, --task-file or --edit. A
+// template's task, if any, is what --edit starts from.
+func readTask(cmd *cobra.Command, dir, tmplTask string) (string, error) {
+	task := newTask
+	switch {
+	case newTask != "" && newTaskFile != "":
+		return "", fmt.Errorf("give --task or --task-file, not both")
+	case tmplTask != "" && (newTask != "" || newTaskFile != ""):
+		return "", fmt.Errorf("give --template or --task/--task-file, not both")
+	case tmplTask != "":
+		task = tmplTask
+	case newTaskFile == "-":
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return "", fmt.Errorf("read task: %w", err)
+		}
+		task = string(data)
+	case newTaskFile != "":
+		data, err := os.ReadFile(newTaskFile)
+		if err != nil {
+			return "", fmt.Errorf("read task: %w", err)
+		}
+		task = string(data)
+	}
+	if newEdit {
+		help := fmt.Sprintf("Describe the task for the worker in %s above the line.\nEverything from it down is ignored; an empty task aborts.", dir)
+		var err error
+		if task, err = editor.Edit(task, help); err != nil {
+			return "", err
+		}
+	}
+	task = strings.TrimSpace(task)
+	if task == "" {
+		if newTask == "" && newTaskFile == "" && !newEdit && tmplTask == "" {
+			return "", fmt.Errorf("give a task with --task, --task-file, --template or --edit")
+		}
+		return "", fmt.Errorf("empty task, not creating a worker")
+	}
+	return task, nil
+}
+
+// fromTemplate loads --template and renders its task and directory with
+// --var. Without --template it returns nothing.
+func fromTemplate() (*templates.Template, string, string, error) {
+	if newTemplate == "" {
+		if len(newVars) > 0 {
+			return nil, "", "", fmt.Errorf("--var needs --template")
+		}
+		return nil, "", "", nil
+	}
+	t, err := templates.Load(templates.Dir(configPath), newTemplate)
+	if err != nil {
+		return nil, "", "", err
+	}
+	vars := map[string]string{}
+	for _, kv := range newVars {
+		name, value, err := templates.ParseVar(kv)
+		if err != nil {
+			return nil, "", "", err
+		}
+		vars[name] = value
+	}
+	task, dir, err := t.Render(vars)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return t, task, dir, nil
+}
+
 func runNew(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load(configPath)
+	tmpl, tmplTask, dir, err := fromTemplate()
 	if err != nil {
+		return err
+	}
+	if newDir != "" {
+		dir = newDir
+	}
+	if dir == "" {
+		return fmt.Errorf("give a project directory with --dir")
+	}
+	profile := newProfile
+	var labels []string
+	if tmpl != nil {
+		if profile == "" {
+			profile = tmpl.Profile
+		}
+		labels = tmpl.Labels
+	}
+	for _, l := range newLabels {
+		if !slices.Contains(labels, l) {
+			labels = append(labels, l)
+		}
+	}
+
+	cfg, err := config.LoadFor(configPath, dir)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	warnIgnored(cmd, cfg)
+
+	isolation, err := worker.Isolation(cfg.Worker.Isolation, newWorktree)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if _, err := cfg.WithProfile(profile); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if _, err := worker.RunnerFor(cfg, newRunner); err != nil {
 		return fmt.Errorf("config: %w", err)
 	}
+	runner := newRunner
+	if runner == worker.DefaultRunner {
+		runner = ""
+	}
+
+	timeout := cfg.Worker.DefaultTimeout.Duration
+	if cmd.Flags().Changed("timeout") {
+		timeout = newTimeout.Duration
+	}
+	if timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	retries := cfg.Worker.Retries
+	if cmd.Flags().Changed("retries") {
+		retries = newRetries
+	}
+	if retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+
+	var env map[string]string
+	for _, kv := range newEnv {
+		k, v, err := worker.ParseEnv(kv)
+		if err != nil {
+			return err
+		}
+		if env == nil {
+			env = map[string]string{}
+		}
+		env[k] = v
+	}
+	var envFiles []string
+	for _, path := range newEnvFiles {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if _, err := worker.ReadEnvFile(abs); err != nil {
+			return err
+		}
+		envFiles = append(envFiles, abs)
+	}
+
+	attach := newAttach
+	if newImage != "" {
+		attach = append(attach, newImage)
+	}
+	attachments, err := worker.ResolveAttachments(attach)
+	if err != nil {
+		return err
+	}
+	ctxFiles, err := worker.ResolveContext(newContext)
+	if err != nil {
+		return err
+	}
+
+	var after []string
+	for _, ref := range newAfter {
+		id, err := state.ResolveAny(stateDir, ref)
+		if err != nil {
+			return fmt.Errorf("--after: %w", err)
+		}
+		if !slices.Contains(after, id) {
+			after = append(after, id)
+		}
+	}
+	if (newAfterAny || newOnFail) && len(after) == 0 {
+		return fmt.Errorf("--after-any and --on-failure need --after")
+	}
+
+	task, err := readTask(cmd, dir, tmplTask)
+	if err != nil {
+		return err
+	}
 
 	now := time.Now()
-	id := strconv.FormatInt(now.Unix(), 10)
 
-	status := state.StatusWorking
-	if newPending {
+	status := state.StatusQueued
+	switch {
+	case newPending:
 		status = state.StatusPending
+	case len(after) > 0:
+		status = state.StatusBlocked
 	}
 
 	w := &state.Worker{
-		ID:        id,
-		Status:    status,
-		Directory: newDir,
-		Task:      newTask,
-		Image:     newImage,
-		SessionID: uuid.New().String(),
-		CreatedAt: &now,
+		Status:      status,
+		Directory:   dir,
+		Task:        task,
+		SessionID:   uuid.New().String(),
+		Runner:      runner,
+		Profile:     profile,
+		Labels:      labels,
+		Env:         env,
+		EnvFiles:    envFiles,
+		Attachments: attachments,
+		Context:     ctxFiles,
+		After:       after,
+		AfterAny:    newAfterAny,
+		OnFailure:   newOnFail,
+		Priority:    newPriority,
+		TimeoutMS:   timeout.Milliseconds(),
+		Retries:     retries,
+		Isolation:   isolation,
+		CreatedAt:   &now,
+	}
+	if status == state.StatusQueued {
+		w.QueuedAt = &now
+	}
+	if tmpl != nil {
+		w.Template = tmpl.Name
 	}
 
-	if err := state.Write(stateDir, w); err != nil {
+	if err := state.Create(stateDir, w); err != nil {
 		return fmt.Errorf("write state: %w", err)
 	}
+	id := w.ID
+	state.AppendEvent(stateDir, id, state.Event{Type: state.EventCreated, Status: status, Actor: state.Actor("cli")})
 
-	vars := hooks.Vars{ID: id, Task: newTask, Dir: newDir, Status: string(status)}
 	if newPending {
-		hooks.Fire(cfg.Hooks.OnPending, vars)
+		worker.FireHooks(stateDir, "on_pending", worker.ConfigFor(cfg, w).Hooks.OnPending, worker.HookVars(w))
 	} else {
-		startedAt := time.Now()
-		w.StartedAt = &startedAt
-		state.Write(stateDir, w)
-
-		cclBin, _ := os.Executable()
-		if err := worker.SpawnRun(id, cclBin, configPath, stateDir); err != nil {
-			return fmt.Errorf("spawn worker: %w", err)
+		if w, err = worker.Schedule(stateDir, configPath, cfg, id); err != nil {
+			return err
 		}
-		hooks.Fire(cfg.Hooks.OnStart, vars)
+		status = w.Status
 	}
 
 	if newJSON {
@@ -2,14 +2,33 @@ package main
 
 import (
 	"encoding/json"
+	"io"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/scottstav/wreccless/internal/config"
 	"github.com/scottstav/wreccless/internal/state"
+	"github.com/spf13/pflag"
 )
 
+func resetNewFlags() {
+	newDir, newTask, newTaskFile, newEdit = "", "", "", false
+	newImage, newAttach, newContext = "", nil, nil
+	newPending, newPriority, newWorktree = false, 0, false
+	newTimeout, newRetries = config.Duration{}, 0
+	newRunner, newProfile = "", ""
+	newEnv, newEnvFiles = nil, nil
+	newTemplate, newVars, newLabels = "", nil, nil
+	newAfter, newAfterAny, newOnFail = nil, false, false
+	newJSON = false
+	// Flags set in an earlier run still count as changed.
+	newCmd.Flags().VisitAll(func(f *pflag.Flag) { f.Changed = false })
+}
+
 func TestNewPending(t *testing.T) {
+	resetNewFlags()
 	dir := t.TempDir()
 	stateDir = dir
 	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
@@ -48,6 +67,7 @@ func TestNewPending(t *testing.T) {
 }
 
 func TestNewPendingJSON(t *testing.T) {
+	resetNewFlags()
 	dir := t.TempDir()
 	stateDir = dir
 	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
@@ -69,3 +89,354 @@ func TestNewPendingJSON(t *testing.T) {
 		t.Error("expected id in JSON output")
 	}
 }
+
+func TestNewQueuesWhenSlotsFull(t *testing.T) {
+	resetNewFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(configPath, []byte("[worker]\nmax_concurrent = 1\n"), 0644)
+	state.Write(dir, &state.Worker{ID: "700", Status: state.StatusWorking, Directory: "/tmp", Task: "busy", RunnerPID: os.Getpid()})
+	newPending, newPriority = false, 0
+	defer func() { newJSON, newPriority = false, 0 }()
+
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/testproject", "--task", "wait", "--priority", "3", "--json"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	var out map[string]string
+	json.Unmarshal([]byte(buf.String()), &out)
+	if out["status"] != "queued" {
+		t.Fatalf("expected queued, got %v", out)
+	}
+	w, _ := state.Read(dir, out["id"])
+	if w.Priority != 3 || w.QueuedAt == nil || w.StartedAt != nil {
+		t.Errorf("unexpected queued worker: %+v", w)
+	}
+}
+
+func TestNewTimeout(t *testing.T) {
+	resetNewFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(configPath, []byte("[worker]\ndefault_timeout = \"1h\"\n"), 0644)
+	newTimeout.Duration = 0
+	newCmd.Flags().Lookup("timeout").Changed = false
+
+	create := func(args ...string) *state.Worker {
+		t.Helper()
+		rootCmd.SetArgs(append([]string{"new", "--dir", "/tmp/proj", "--task", "t", "--pending"}, args...))
+		buf := new(strings.Builder)
+		rootCmd.SetOut(buf)
+		rootCmd.SetErr(buf)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+		w, err := state.Read(dir, strings.TrimSpace(buf.String()))
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return w
+	}
+
+	if w := create(); w.TimeoutMS != 3600000 {
+		t.Errorf("expected default_timeout, got %dms", w.TimeoutMS)
+	}
+	if w := create("--timeout", "30m"); w.TimeoutMS != 1800000 {
+		t.Errorf("expected --timeout to win, got %dms", w.TimeoutMS)
+	}
+	if w := create("--timeout", "0"); w.TimeoutMS != 0 {
+		t.Errorf("expected --timeout 0 to disable the default, got %dms", w.TimeoutMS)
+	}
+}
+
+func TestNewUnknownRunner(t *testing.T) {
+	resetNewFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
+
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "t", "--pending", "--runner", "nope"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	err := rootCmd.Execute()
+	newRunner = ""
+	if err == nil || !strings.Contains(err.Error(), `unknown runner "nope"`) {
+		t.Errorf("expected unknown runner error, got %v", err)
+	}
+	if workers, _ := state.List(dir); len(workers) != 0 {
+		t.Errorf("no worker should be created, got %d", len(workers))
+	}
+}
+
+func TestNewProfile(t *testing.T) {
+	resetNewFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	marker := filepath.Join(t.TempDir(), "pending")
+	os.WriteFile(configPath, []byte("[profiles.review]\nskip_permissions = false\n\n[profiles.review.hooks]\non_pending = [\"touch "+marker+"\"]\n"), 0644)
+	defer func() { newProfile = "" }()
+
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "t", "--pending", "--profile", "review"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	w, err := state.Read(dir, strings.TrimSpace(buf.String()))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if w.Profile != "review" {
+		t.Errorf("expected profile review, got %q", w.Profile)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected the profile's on_pending hook to run: %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "t", "--pending", "--profile", "nope"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), `unknown profile "nope"`) {
+		t.Errorf("expected unknown profile error, got %v", err)
+	}
+}
+
+func TestNewEnv(t *testing.T) {
+	resetNewFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
+	secrets := filepath.Join(t.TempDir(), "secrets.env")
+	os.WriteFile(secrets, []byte("API_KEY=hunter2\n"), 0600)
+	defer func() { newEnv, newEnvFiles = nil, nil }()
+
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "t", "--pending",
+		"--env", "AWS_PROFILE=dev", "--env", "BASE_URL=http://localhost?a=b", "--env-file", secrets})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	id := strings.TrimSpace(buf.String())
+	w, err := state.Read(dir, id)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if w.Env["AWS_PROFILE"] != "dev" || w.Env["BASE_URL"] != "http://localhost?a=b" {
+		t.Errorf("unexpected env %v", w.Env)
+	}
+	if len(w.EnvFiles) != 1 || w.EnvFiles[0] != secrets {
+		t.Errorf("expected the env file path stored, got %v", w.EnvFiles)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, id+".json"))
+	if strings.Contains(string(data), "hunter2") {
+		t.Error("env file contents must not be written to state")
+	}
+
+	newEnvFiles = nil
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "t", "--pending", "--env", "NOEQUALS"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected an error for --env without =")
+	}
+}
+
+func TestNewAttachAndContext(t *testing.T) {
+	resetNewFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
+	files := t.TempDir()
+	for _, name := range []string{"mock.png", "spec.pdf", "a.md", "b.md"} {
+		os.WriteFile(filepath.Join(files, name), []byte(name), 0644)
+	}
+	defer func() { newAttach, newContext, newImage = nil, nil, "" }()
+
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "t", "--pending",
+		"--attach", filepath.Join(files, "spec.pdf"), "--image", filepath.Join(files, "mock.png"),
+		"--context", filepath.Join(files, "*.md")})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(io.Discard)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	// The --image deprecation notice is printed first.
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	id := lines[len(lines)-1]
+	w, err := state.Read(dir, id)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	wantAttach := []string{filepath.Join(files, "spec.pdf"), filepath.Join(files, "mock.png")}
+	if strings.Join(w.Attachments, " ") != strings.Join(wantAttach, " ") {
+		t.Errorf("expected attachments %v, got %v", wantAttach, w.Attachments)
+	}
+	if len(w.Context) != 2 {
+		t.Errorf("expected the glob expanded to 2 context files, got %v", w.Context)
+	}
+
+	newImage, newContext = "", nil
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "t", "--pending",
+		"--attach", filepath.Join(files, "missing.png")})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected an error attaching a missing file")
+	}
+}
+
+func TestNewTaskFromStdinAndEditor(t *testing.T) {
+	resetNewFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
+	defer func() { newTask, newTaskFile, newEdit = "", "", false }()
+
+	create := func(stdin string, args ...string) *state.Worker {
+		t.Helper()
+		newTask, newTaskFile, newEdit = "", "", false
+		rootCmd.SetArgs(append([]string{"new", "--dir", "/tmp/proj", "--pending"}, args...))
+		rootCmd.SetIn(strings.NewReader(stdin))
+		buf := new(strings.Builder)
+		rootCmd.SetOut(buf)
+		rootCmd.SetErr(buf)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+		w, err := state.Read(dir, strings.TrimSpace(buf.String()))
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return w
+	}
+
+	spec := "# Add rate limiting\n\n" + strings.Repeat("Details of the spec.\n", 200)
+	w := create(spec, "--task-file", "-")
+	if w.TaskFile == "" || w.Task != "# Add rate limiting" {
+		t.Errorf("expected a long task stored in a file with a summary, got %q (file %q)", w.Task, w.TaskFile)
+	}
+	if full, err := state.ReadTask(dir, w); err != nil || full != strings.TrimSpace(spec) {
+		t.Errorf("expected the full task back, got %d bytes (%v)", len(full), err)
+	}
+
+	script := filepath.Join(t.TempDir(), "ed")
+	os.WriteFile(script, []byte("#!/bin/sh\nsed -i '1s/^/Carefully: /' \"$1\"\n"), 0755)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", script)
+	if w := create("", "--task", "fix the bug", "--edit"); w.Task != "Carefully: fix the bug" || w.TaskFile != "" {
+		t.Errorf("expected the edited task inline, got %q (file %q)", w.Task, w.TaskFile)
+	}
+
+	newTask, newTaskFile, newEdit = "", "", false
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--pending"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--task-file") {
+		t.Errorf("expected a missing task error, got %v", err)
+	}
+}
+
+func TestNewTemplate(t *testing.T) {
+	resetNewFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(configPath, []byte("[profiles.careful]\nskip_permissions = false\n"), 0644)
+	os.Mkdir(filepath.Join(filepath.Dir(configPath), "templates"), 0755)
+	os.WriteFile(filepath.Join(filepath.Dir(configPath), "templates", "review.toml"), []byte(`
+task = "Review branch {{.branch}}"
+dir = "/tmp/proj"
+profile = "careful"
+labels = ["review"]
+`), 0644)
+	defer func() { newTemplate, newVars, newLabels = "", nil, nil }()
+
+	rootCmd.SetArgs([]string{"new", "--template", "review", "--var", "branch=fix-login", "--label", "urgent", "--pending"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	w, err := state.Read(dir, strings.TrimSpace(buf.String()))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if w.Task != "Review branch fix-login" || w.Directory != "/tmp/proj" || w.Profile != "careful" || w.Template != "review" {
+		t.Errorf("unexpected worker from template: task %q dir %q profile %q template %q", w.Task, w.Directory, w.Profile, w.Template)
+	}
+	if strings.Join(w.Labels, ",") != "review,urgent" {
+		t.Errorf("expected template labels plus --label, got %v", w.Labels)
+	}
+
+	newVars, newLabels = nil, nil
+	rootCmd.SetArgs([]string{"new", "--template", "review", "--pending"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "branch") {
+		t.Errorf("expected an error naming the missing var, got %v", err)
+	}
+	rootCmd.SetArgs([]string{"new", "--template", "review", "--var", "branch=x", "--task", "other", "--pending"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected an error giving both --template and --task")
+	}
+}
+
+func TestNewAfter(t *testing.T) {
+	resetNewFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "nonexistent.toml")
+	statusJSON = false
+	state.Write(dir, &state.Worker{ID: "tdep1abc", Status: state.StatusWorking, Directory: "/tmp/proj", Task: "migrate"})
+	state.Write(dir, &state.Worker{ID: "tdep2abc", Status: state.StatusDone, Directory: "/tmp/proj", Task: "seed"})
+	newPending = false
+	defer func() { newAfter, newAfterAny, newOnFail = nil, false, false }()
+
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "run tests", "--after", "tdep1,tdep2", "--json"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	var out map[string]string
+	json.Unmarshal([]byte(buf.String()), &out)
+	if out["status"] != "blocked" {
+		t.Fatalf("expected blocked while tdep1abc runs, got %q", out["status"])
+	}
+	w, _ := state.Read(dir, out["id"])
+	if strings.Join(w.After, ",") != "tdep1abc,tdep2abc" || w.QueuedAt != nil {
+		t.Errorf("expected resolved dependencies and no queue time, got %v %v", w.After, w.QueuedAt)
+	}
+
+	buf.Reset()
+	rootCmd.SetArgs([]string{"status", "tdep1abc"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if want := "Before:     " + w.ID + " (blocked)"; !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %q in:\n%s", want, buf.String())
+	}
+	buf.Reset()
+	rootCmd.SetArgs([]string{"status", w.ID})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, want := range []string{"After:      tdep1abc (working)", "After:      tdep2abc (done)", "Runs when:  all succeed"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, buf.String())
+		}
+	}
+
+	newAfter = nil
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "t", "--on-failure"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected an error for --on-failure without --after")
+	}
+	newOnFail = false
+	rootCmd.SetArgs([]string{"new", "--dir", "/tmp/proj", "--task", "t", "--after", "nosuchworker"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected an error for an unknown --after worker")
+	}
+}
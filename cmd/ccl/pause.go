@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause <id>",
+	Short: "Suspend a running worker (SIGSTOP)",
+	Long: `Suspend a running worker by sending SIGSTOP to claude's process group.
+
+A paused worker keeps its concurrency slot and can still be killed. Time spent
+paused doesn't count towards its timeout. Continue it with "ccl unpause".`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPause,
+}
+
+var unpauseCmd = &cobra.Command{
+	Use:   "unpause <id>",
+	Short: "Continue a paused worker (SIGCONT)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUnpause,
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(unpauseCmd)
+}
+
+func runPause(cmd *cobra.Command, args []string) error {
+	id, err := state.Resolve(stateDir, args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := worker.Pause(stateDir, id, state.Actor("cli")); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Paused worker %s\n", id)
+	return nil
+}
+
+func runUnpause(cmd *cobra.Command, args []string) error {
+	id, err := state.Resolve(stateDir, args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := worker.Unpause(stateDir, id, state.Actor("cli")); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Unpaused worker %s\n", id)
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestPauseRequiresRunningProcess(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	state.Write(dir, &state.Worker{ID: "805", Status: state.StatusQueued, Directory: "/tmp", Task: "q"})
+
+	rootCmd.SetArgs([]string{"pause", "805"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected pausing a queued worker to fail")
+	}
+	if w, _ := state.Read(dir, "805"); w.Status != state.StatusQueued {
+		t.Errorf("expected worker to stay queued, got %s", w.Status)
+	}
+}
@@ -9,12 +9,13 @@ import (
 
 	"github.com/scottstav/wreccless/internal/config"
 	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
 	"github.com/spf13/cobra"
 )
 
 var resumeCmd = &cobra.Command{
 	Use:   "resume <id>",
-	Short: "Resume a worker's claude session interactively",
+	Short: "Resume a worker's agent session interactively",
 	Args:  cobra.ExactArgs(1),
 	RunE:  runResume,
 }
@@ -27,7 +28,10 @@ func init() {
 }
 
 func runResume(cmd *cobra.Command, args []string) error {
-	id := args[0]
+	id, err := state.Resolve(stateDir, args[0])
+	if err != nil {
+		return err
+	}
 	w, err := state.Read(stateDir, id)
 	if err != nil {
 		return fmt.Errorf("worker %s not found", id)
@@ -36,26 +40,40 @@ func runResume(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("worker %s has no session to resume", id)
 	}
 
-	cfg, _ := config.Load(configPath)
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
 
-	claudeArgs := []string{"--resume", w.SessionID}
-	if cfg.Claude.SkipPermissions {
-		claudeArgs = append(claudeArgs, "--dangerously-skip-permissions")
+	if w.Task, err = state.ReadTask(stateDir, w); err != nil {
+		return err
+	}
+	cfg = worker.ConfigFor(cfg, w)
+	agent, err := worker.RunnerFor(cfg, w.Runner)
+	if err != nil {
+		return err
+	}
+	env, err := worker.Environ(stateDir, w, cfg)
+	if err != nil {
+		return err
+	}
+	argv, err := agent.InteractiveCommand(w)
+	if err != nil {
+		return err
 	}
 
 	if resumeDryRun {
-		fmt.Fprintf(cmd.OutOrStdout(), "cd %s && claude %s\n", w.Directory, strings.Join(claudeArgs, " "))
+		fmt.Fprintf(cmd.OutOrStdout(), "cd %s && %s\n", worker.WorkDir(w), strings.Join(argv, " "))
 		return nil
 	}
 
-	// Clean up state file since user is taking over
-	state.Delete(stateDir, id)
+	state.AppendEvent(stateDir, id, state.Event{Type: state.EventResumed, Actor: state.Actor("cli")})
 
-	os.Chdir(w.Directory)
+	os.Chdir(worker.WorkDir(w))
 
-	claudePath, err := exec.LookPath("claude")
+	path, err := exec.LookPath(argv[0])
 	if err != nil {
-		return fmt.Errorf("claude not found in PATH")
+		return fmt.Errorf("%s not found in PATH", argv[0])
 	}
-	return syscall.Exec(claudePath, append([]string{"claude"}, claudeArgs...), os.Environ())
+	return syscall.Exec(path, argv, env)
 }
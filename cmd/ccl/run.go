@@ -1,7 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
 	"github.com/scottstav/wreccless/internal/worker"
 	"github.com/spf13/cobra"
 )
@@ -21,7 +26,29 @@ func init() {
 func runRun(cmd *cobra.Command, args []string) error {
 	cfg, err := config.Load(configPath)
 	if err != nil {
-		return err
+		// Promote has already started the worker; don't leave it holding
+		// a slot with no runner.
+		reason := "config: " + err.Error()
+		_, terr := state.Transition(stateDir, args[0], []state.Status{state.StatusWorking}, func(w *state.Worker) error {
+			if w.RunnerPID != 0 {
+				return fmt.Errorf("worker %s is already running (pid %d)", w.ID, w.RunnerPID)
+			}
+			now := time.Now()
+			w.Status = state.StatusError
+			w.FinishedAt = &now
+			w.ErrorReason = reason
+			return nil
+		})
+		if terr == nil {
+			state.AppendEvent(stateDir, args[0], state.Event{Type: state.EventFinished, Status: state.StatusError, Error: reason})
+		}
+		return fmt.Errorf("config: %w", err)
 	}
-	return worker.Run(stateDir, args[0], cfg, "")
+	runErr := worker.Run(stateDir, args[0], cfg, "")
+
+	// This worker's slot is free now (or was never taken); hand it on, and
+	// settle the workers that were waiting for it to finish.
+	cclBin, _ := os.Executable()
+	worker.Promote(stateDir, cfg, worker.SpawnLauncher(cclBin, configPath, stateDir))
+	return runErr
 }
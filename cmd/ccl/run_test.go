@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestRunBadConfig(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(configPath, []byte("[worker\n"), 0644)
+	now := time.Now()
+	state.Write(dir, &state.Worker{ID: "1500", Status: state.StatusWorking, Directory: "/tmp", Task: "t", StartedAt: &now})
+
+	rootCmd.SetArgs([]string{"run", "1500"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err == nil || !strings.HasPrefix(err.Error(), "config:") {
+		t.Errorf("expected a config error, got %v", err)
+	}
+	w, _ := state.Read(dir, "1500")
+	if w.Status != state.StatusError || !strings.HasPrefix(w.ErrorReason, "config:") || w.FinishedAt == nil {
+		t.Errorf("expected the worker errored with the config error, got %+v", w)
+	}
+}
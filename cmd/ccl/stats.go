@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize worker outcomes, durations and cost",
+	Long: `Summarize worker outcomes, durations and cost.
+
+DONE counts workers that finished done and FAILED those that finished any
+other way: error, timeout, killed or denied. SUCCESS is DONE over the two
+together; workers still running, queued or pending count toward neither.`,
+	RunE: runStats,
+}
+
+var (
+	statsBy    string
+	statsSince string
+	statsDir   string
+	statsJSON  bool
+	statsCSV   bool
+)
+
+func init() {
+	statsCmd.Flags().StringVar(&statsBy, "by", "dir", "Group by ("+strings.Join(stats.Groupings, "|")+")")
+	statsCmd.Flags().StringVar(&statsSince, "since", "", "Only workers created since a date (2006-01-02) or duration ago (7d, 12h)")
+	statsCmd.Flags().StringVar(&statsDir, "dir", "", "Only workers in this directory or below it")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Output JSON")
+	statsCmd.Flags().BoolVar(&statsCSV, "csv", false, "Output CSV")
+	rootCmd.AddCommand(statsCmd)
+}
+
+// parseSince accepts a date or a duration before now.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	var d config.Duration
+	if err := d.UnmarshalText([]byte(s)); err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: want a date (2006-01-02) or a duration (7d)", s)
+	}
+	return now.Add(-d.Duration), nil
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if statsJSON && statsCSV {
+		return fmt.Errorf("--json and --csv are mutually exclusive")
+	}
+	filter := stats.Filter{Dir: statsDir}
+	if statsSince != "" {
+		since, err := parseSince(statsSince, time.Now())
+		if err != nil {
+			return err
+		}
+		filter.Since = since
+	}
+	if filter.Dir != "" {
+		if home, _ := os.UserHomeDir(); home != "" && strings.HasPrefix(filter.Dir, "~") {
+			filter.Dir = home + filter.Dir[1:]
+		}
+	}
+
+	workers, err := state.ListAll(stateDir)
+	if err != nil {
+		return err
+	}
+	groups, err := stats.Compute(stats.Select(workers, filter), statsBy)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	switch {
+	case statsJSON:
+		if groups == nil {
+			groups = []stats.Group{}
+		}
+		data, err := json.Marshal(groups)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	case statsCSV:
+		cw := csv.NewWriter(out)
+		cw.Write([]string{statsBy, "count", "done", "failed", "success_rate", "median_duration_ms", "total_cost_usd", "input_tokens", "output_tokens"})
+		for _, g := range groups {
+			cw.Write([]string{
+				g.Key,
+				fmt.Sprint(g.Count),
+				fmt.Sprint(g.ByStatus[string(state.StatusDone)]),
+				fmt.Sprint(g.Failed),
+				fmt.Sprintf("%.4f", g.SuccessRate),
+				fmt.Sprint(g.MedianDuration.Milliseconds()),
+				fmt.Sprintf("%.4f", g.TotalCostUSD),
+				fmt.Sprint(g.InputTokens),
+				fmt.Sprint(g.OutputTokens),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	if len(groups) == 0 {
+		fmt.Fprintln(out, "No workers.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tCOUNT\tDONE\tFAILED\tSUCCESS\tMEDIAN\tCOST\n", strings.ToUpper(statsBy))
+	var total stats.Group
+	for _, g := range groups {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.0f%%\t%s\t$%.2f\n",
+			g.Key, g.Count,
+			g.ByStatus[string(state.StatusDone)], g.Failed,
+			g.SuccessRate*100, g.MedianDuration.Round(time.Second), g.TotalCostUSD)
+		total.Count += g.Count
+		total.TotalCostUSD += g.TotalCostUSD
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\t\t\t\t\t$%.2f\n", total.Count, total.TotalCostUSD)
+	tw.Flush()
+	return nil
+}
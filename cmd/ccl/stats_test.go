@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func resetStatsFlags() {
+	statsBy = "dir"
+	statsSince = ""
+	statsDir = ""
+	statsJSON = false
+	statsCSV = false
+}
+
+func TestStatsIncludesArchived(t *testing.T) {
+	resetStatsFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	now := time.Now()
+	state.Write(dir, &state.Worker{ID: "600", Status: state.StatusDone, Directory: "/tmp/s", Task: "a", CreatedAt: &now, CostUSD: 1})
+	state.Write(dir, &state.Worker{ID: "601", Status: state.StatusError, Directory: "/tmp/s", Task: "b", CreatedAt: &now, CostUSD: 2})
+	if _, err := state.Archive(dir, "601", nil); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"stats", "--json"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	var groups []map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &groups); err != nil {
+		t.Fatalf("json: %v\n%s", err, buf.String())
+	}
+	if len(groups) != 1 || groups[0]["count"] != 2.0 || groups[0]["total_cost_usd"] != 3.0 || groups[0]["success_rate"] != 0.5 {
+		t.Errorf("unexpected stats: %v", groups)
+	}
+}
+
+func TestStatsCSVSince(t *testing.T) {
+	resetNewFlags()
+	resetStatsFlags()
+	dir := t.TempDir()
+	stateDir = dir
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour)
+	state.Write(dir, &state.Worker{ID: "610", Status: state.StatusDone, Directory: "/tmp/s", Task: "new", CreatedAt: &now})
+	state.Write(dir, &state.Worker{ID: "611", Status: state.StatusDone, Directory: "/tmp/s", Task: "old", CreatedAt: &old})
+
+	rootCmd.SetArgs([]string{"stats", "--by", "status", "--since", "7d", "--csv"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "status,count") || !strings.HasPrefix(lines[1], "done,1,") {
+		t.Errorf("unexpected csv:\n%s", buf.String())
+	}
+}
@@ -3,8 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
 	"github.com/spf13/cobra"
 )
 
@@ -23,9 +28,13 @@ func init() {
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
-	w, err := state.Read(stateDir, args[0])
+	id, err := state.ResolveAny(stateDir, args[0])
 	if err != nil {
-		return fmt.Errorf("worker %s not found", args[0])
+		return err
+	}
+	w, err := state.ReadAny(stateDir, id)
+	if err != nil {
+		return fmt.Errorf("worker %s not found", id)
 	}
 
 	if statusJSON {
@@ -35,11 +44,71 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Fprintf(cmd.OutOrStdout(), "ID:         %s\n", w.ID)
-	fmt.Fprintf(cmd.OutOrStdout(), "Status:     %s\n", w.Status)
+	if w.Archived {
+		fmt.Fprintf(cmd.OutOrStdout(), "Status:     %s (archived)\n", w.Status)
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Status:     %s\n", w.Status)
+	}
 	fmt.Fprintf(cmd.OutOrStdout(), "Directory:  %s\n", w.Directory)
 	fmt.Fprintf(cmd.OutOrStdout(), "Task:       %s\n", w.Task)
-	if w.Image != "" {
-		fmt.Fprintf(cmd.OutOrStdout(), "Image:      %s\n", w.Image)
+	if w.TaskFile != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Task file:  %s\n", w.TaskFile)
+	}
+	if w.WorktreePath != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Worktree:   %s\n", w.WorktreePath)
+	}
+	if w.Branch != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Branch:     %s (from %.12s)\n", w.Branch, w.BaseCommit)
+	}
+	for _, p := range w.Attachments {
+		fmt.Fprintf(cmd.OutOrStdout(), "Attachment: %s\n", p)
+	}
+	for _, p := range w.Context {
+		fmt.Fprintf(cmd.OutOrStdout(), "Context:    %s\n", p)
+	}
+	if w.Runner != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Runner:     %s\n", w.Runner)
+	}
+	if w.Priority != 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Priority:   %d\n", w.Priority)
+	}
+	if w.Profile != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Profile:    %s\n", w.Profile)
+	}
+	if w.Template != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Template:   %s\n", w.Template)
+	}
+	if len(w.Labels) > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Labels:     %s\n", strings.Join(w.Labels, ", "))
+	}
+	for _, id := range w.After {
+		dep := "not found"
+		if d, err := state.ReadAny(stateDir, id); err == nil {
+			dep = string(d.Status)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "After:      %s (%s)\n", id, dep)
+	}
+	if len(w.After) > 1 || w.OnFailure {
+		fmt.Fprintf(cmd.OutOrStdout(), "Runs when:  %s\n", worker.AfterCondition(w))
+	}
+	dependents, err := worker.Dependents(stateDir, w.ID)
+	if err != nil {
+		return err
+	}
+	for _, d := range dependents {
+		fmt.Fprintf(cmd.OutOrStdout(), "Before:     %s (%s)\n", d.ID, d.Status)
+	}
+	for _, k := range slices.Sorted(maps.Keys(w.Env)) {
+		fmt.Fprintf(cmd.OutOrStdout(), "Env:        %s=%s\n", k, w.Env[k])
+	}
+	for _, path := range w.EnvFiles {
+		fmt.Fprintf(cmd.OutOrStdout(), "Env file:   %s\n", path)
+	}
+	if w.TimeoutMS > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Timeout:    %s\n", time.Duration(w.TimeoutMS)*time.Millisecond)
+	}
+	if w.Retries > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Attempt:    %d of %d\n", max(w.Attempt, 1), w.Retries+1)
 	}
 	if w.PID > 0 {
 		fmt.Fprintf(cmd.OutOrStdout(), "PID:        %d\n", w.PID)
@@ -50,11 +119,70 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	if w.CreatedAt != nil {
 		fmt.Fprintf(cmd.OutOrStdout(), "Created:    %s\n", w.CreatedAt.Format("2006-01-02 15:04:05"))
 	}
+	if w.QueuedAt != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Queued:     %s\n", w.QueuedAt.Format("2006-01-02 15:04:05"))
+	}
 	if w.StartedAt != nil {
 		fmt.Fprintf(cmd.OutOrStdout(), "Started:    %s\n", w.StartedAt.Format("2006-01-02 15:04:05"))
 	}
+	if w.PausedAt != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Paused:     %s\n", w.PausedAt.Format("2006-01-02 15:04:05"))
+	}
+	if d := w.PausedFor(time.Now()); d > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Paused for: %s\n", d.Round(time.Second))
+	}
 	if w.FinishedAt != nil {
 		fmt.Fprintf(cmd.OutOrStdout(), "Finished:   %s\n", w.FinishedAt.Format("2006-01-02 15:04:05"))
 	}
+	if w.ExitCode != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Exit code:  %d\n", *w.ExitCode)
+	}
+	if w.Signal != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Signal:     %s\n", w.Signal)
+	}
+	if w.ResultSubtype != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Result:     %s\n", w.ResultSubtype)
+	}
+	if w.ErrorReason != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Reason:     %s\n", w.ErrorReason)
+	}
+	if g := w.Git; g != nil {
+		dirty := ""
+		if g.StartDirty {
+			dirty = ", dirty"
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Git:        %s @ %.12s%s\n", g.Branch, g.StartHead, dirty)
+		if len(g.Commits) > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "Commits:    %d\n", len(g.Commits))
+			for _, c := range g.Commits {
+				fmt.Fprintf(cmd.OutOrStdout(), "              %s\n", c)
+			}
+		}
+		if g.End != "" {
+			added, deleted := 0, 0
+			for _, f := range g.Files {
+				added += max(f.Added, 0)
+				deleted += max(f.Deleted, 0)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Changes:    %d file(s), +%d -%d\n", len(g.Files), added, deleted)
+			for _, f := range g.Files {
+				if f.Added < 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "              %s (binary)\n", f.Path)
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "              %s +%d -%d\n", f.Path, f.Added, f.Deleted)
+				}
+			}
+			for _, path := range g.Untracked {
+				fmt.Fprintf(cmd.OutOrStdout(), "              %s (untracked)\n", path)
+			}
+		}
+	}
+	if w.NumTurns > 0 || w.CostUSD > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Cost:       $%.4f\n", w.CostUSD)
+		fmt.Fprintf(cmd.OutOrStdout(), "Turns:      %d\n", w.NumTurns)
+		fmt.Fprintf(cmd.OutOrStdout(), "Tokens:     %d in, %d out, %d cache read, %d cache write\n",
+			w.InputTokens, w.OutputTokens, w.CacheReadTokens, w.CacheCreationTokens)
+		fmt.Fprintf(cmd.OutOrStdout(), "Duration:   %s\n", (time.Duration(w.DurationMS) * time.Millisecond).Round(time.Second))
+	}
 	return nil
 }
@@ -59,3 +59,48 @@ func TestStatusNotFound(t *testing.T) {
 		t.Fatal("expected error for nonexistent worker")
 	}
 }
+
+func TestStatusPrefix(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	statusJSON = false
+	state.Write(dir, &state.Worker{ID: "tq3k9abc", Status: state.StatusDone, Directory: "/tmp", Task: "prefixed"})
+	state.Write(dir, &state.Worker{ID: "tq3k9xyz", Status: state.StatusDone, Directory: "/tmp", Task: "other"})
+
+	rootCmd.SetArgs([]string{"status", "tq3k9a"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !strings.Contains(buf.String(), "tq3k9abc") {
+		t.Errorf("expected resolved ID: %s", buf.String())
+	}
+
+	rootCmd.SetArgs([]string{"status", "tq3k9"})
+	rootCmd.SetErr(buf)
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("expected ambiguous error, got %v", err)
+	}
+}
+
+func TestStatusExitOutcome(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	code := 1
+	state.Write(dir, &state.Worker{ID: "510", Status: state.StatusError, Directory: "/tmp", Task: "t", ExitCode: &code, ResultSubtype: "error_max_turns", ErrorReason: "error_max_turns (exit status 1)"})
+
+	rootCmd.SetArgs([]string{"status", "510"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	statusJSON = false
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	for _, want := range []string{"Exit code:  1", "Result:     error_max_turns", "Reason:     error_max_turns (exit status 1)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
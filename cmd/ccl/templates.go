@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/scottstav/wreccless/internal/templates"
+	"github.com/spf13/cobra"
+)
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List task templates",
+	Long: `List the task templates usable with ccl new --template.
+
+Templates are TOML files in the templates directory beside the config file
+(~/.config/ccl/templates/<name>.toml by default).`,
+	Args: cobra.NoArgs,
+	RunE: runTemplates,
+}
+
+var templatesJSON bool
+
+func init() {
+	templatesCmd.Flags().BoolVar(&templatesJSON, "json", false, "Output JSON")
+	rootCmd.AddCommand(templatesCmd)
+}
+
+func runTemplates(cmd *cobra.Command, args []string) error {
+	dir := templates.Dir(configPath)
+	list, err := templates.List(dir)
+	if err != nil {
+		return err
+	}
+
+	if templatesJSON {
+		data, err := json.Marshal(list)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return nil
+	}
+
+	if len(list) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "No templates in %s.\n", dir)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVARS\tDESCRIPTION")
+	for _, t := range list {
+		var vars []string
+		for _, v := range t.Vars {
+			switch {
+			case v.DefaultValue() != "":
+				vars = append(vars, v.Name+"="+v.DefaultValue())
+			case !v.IsRequired():
+				vars = append(vars, v.Name+"?")
+			default:
+				vars = append(vars, v.Name)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Name, strings.Join(vars, " "), t.Description)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplatesList(t *testing.T) {
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	tmplDir := filepath.Join(filepath.Dir(configPath), "templates")
+	os.Mkdir(tmplDir, 0755)
+	os.WriteFile(filepath.Join(tmplDir, "bump.toml"), []byte(`
+description = "Bump a dependency"
+task = "Bump {{.dep}} to {{.version}}"
+
+[[vars]]
+name = "version"
+default = "latest"
+`), 0644)
+
+	rootCmd.SetArgs([]string{"templates"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"NAME", "bump", "version=latest dep", "Bump a dependency"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
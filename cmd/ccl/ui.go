@@ -7,7 +7,6 @@ import (
 	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/scottstav/wreccless/internal/config"
 	"github.com/scottstav/wreccless/internal/tui"
 	"github.com/spf13/cobra"
 )
@@ -33,19 +32,14 @@ func runUI(cmd *cobra.Command, args []string) error {
 
 	// Check if we need to resume a session
 	if a, ok := finalModel.(tui.App); ok && a.ResumeWorker != nil {
-		cfg, _ := config.Load(configPath)
-		claudeArgs := []string{"--resume", a.ResumeWorker.SessionID}
-		if cfg.Claude.SkipPermissions {
-			claudeArgs = append(claudeArgs, "--dangerously-skip-permissions")
-		}
-
+		argv := a.ResumeWorker.Command
 		os.Chdir(a.ResumeWorker.Directory)
 
-		claudePath, err := exec.LookPath("claude")
+		path, err := exec.LookPath(argv[0])
 		if err != nil {
-			return fmt.Errorf("claude not found in PATH")
+			return fmt.Errorf("%s not found in PATH", argv[0])
 		}
-		return syscall.Exec(claudePath, append([]string{"claude"}, claudeArgs...), os.Environ())
+		return syscall.Exec(path, argv, a.ResumeWorker.Env)
 	}
 
 	return nil
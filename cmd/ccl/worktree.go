@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+var worktreeCmd = &cobra.Command{
+	Use:   "worktree",
+	Short: "Manage worker git worktrees",
+}
+
+var worktreeRmCmd = &cobra.Command{
+	Use:   "rm <id>...",
+	Short: "Remove a worker's worktree, keeping its ccl/<id> branch",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runWorktreeRm,
+}
+
+var worktreeForce bool
+
+func init() {
+	worktreeRmCmd.Flags().BoolVar(&worktreeForce, "force", false, "Remove even if the worktree has uncommitted changes")
+	worktreeCmd.AddCommand(worktreeRmCmd)
+	rootCmd.AddCommand(worktreeCmd)
+}
+
+func runWorktreeRm(cmd *cobra.Command, args []string) error {
+	for _, ref := range args {
+		id, err := state.Resolve(stateDir, ref)
+		if err != nil {
+			return err
+		}
+		w, err := state.Read(stateDir, id)
+		if err != nil {
+			return fmt.Errorf("worker %s not found", id)
+		}
+		if !w.Status.Finished() {
+			return fmt.Errorf("worker %s is %s; wait for it to finish or kill it first", id, w.Status)
+		}
+		path := w.WorktreePath
+		if err := worker.RemoveWorktree(stateDir, w, worktreeForce); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed worktree %s (branch %s kept)\n", path, w.Branch)
+	}
+	return nil
+}
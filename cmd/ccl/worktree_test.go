@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestWorktreeRmWithoutWorktree(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	state.Write(dir, &state.Worker{ID: "800", Status: state.StatusDone, Directory: "/tmp", Task: "plain"})
+
+	rootCmd.SetArgs([]string{"worktree", "rm", "800"})
+	buf := new(strings.Builder)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "has no worktree") {
+		t.Errorf("expected no-worktree error, got %v", err)
+	}
+}
+
+func TestWorktreeRmRequiresFinished(t *testing.T) {
+	dir := t.TempDir()
+	stateDir = dir
+	for _, status := range []state.Status{state.StatusQueued, state.StatusWorking, state.StatusPaused, state.StatusBlocked} {
+		state.Write(dir, &state.Worker{ID: "801", Status: status, Directory: "/tmp", Task: "t", WorktreePath: "/tmp/wt", Branch: "ccl/801"})
+
+		rootCmd.SetArgs([]string{"worktree", "rm", "801"})
+		buf := new(strings.Builder)
+		rootCmd.SetOut(buf)
+		rootCmd.SetErr(buf)
+		err := rootCmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), "is "+string(status)) {
+			t.Errorf("%s: expected a refusal, got %v", status, err)
+		}
+	}
+}
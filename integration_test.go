@@ -84,10 +84,14 @@ func TestIntegrationLifecycle(t *testing.T) {
 	}
 	t.Logf("Denied: %s", out)
 
-	// 5. List should be empty
-	out, _ = run("list")
-	if !strings.Contains(out, "No workers") {
-		t.Errorf("expected no workers after deny: %s", out)
+	// 5. List should show it as denied
+	out, _ = run("list", "--status", "denied", "--json")
+	workers = nil
+	if err := json.Unmarshal([]byte(out), &workers); err != nil {
+		t.Fatalf("json parse: %v (%s)", err, out)
+	}
+	if len(workers) != 1 || workers[0]["id"] != workerID {
+		t.Errorf("expected denied worker to be kept: %s", out)
 	}
 
 	// 6. Create another pending, then approve it
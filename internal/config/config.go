@@ -1,15 +1,55 @@
 package config
 
 import (
+	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
 
+// Duration is a time.Duration that decodes from strings like "90m" or "7d".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	s := string(text)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		d.Duration = time.Duration(n * float64(24*time.Hour))
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+// Set and Type let a Duration be used as a command-line flag.
+func (d *Duration) Set(s string) error { return d.UnmarshalText([]byte(s)) }
+func (d *Duration) Type() string       { return "duration" }
+
 type ClaudeConfig struct {
-	SkipPermissions bool     `toml:"skip_permissions"`
-	SystemPrompt    string   `toml:"system_prompt"`
-	ExtraFlags      []string `toml:"extra_flags"`
+	SkipPermissions bool              `toml:"skip_permissions"`
+	SystemPrompt    string            `toml:"system_prompt"`
+	ExtraFlags      []string          `toml:"extra_flags"`
+	Env             map[string]string `toml:"env"` // added to the agent's environment
 }
 
 type HooksConfig struct {
@@ -18,13 +58,67 @@ type HooksConfig struct {
 	OnPending []string `toml:"on_pending"`
 	OnError   []string `toml:"on_error"`
 	OnKill    []string `toml:"on_kill"`
+	OnTimeout []string `toml:"on_timeout"`
+}
+
+// RetentionConfig bounds the size of the state directory. Zero values
+// disable the corresponding rule. Only finished workers are ever removed.
+type RetentionConfig struct {
+	MaxAge      map[string]Duration `toml:"max_age"`       // per status, e.g. done = "7d"
+	MaxLogBytes int64               `toml:"max_log_bytes"` // across live and archived logs
+	KeepLast    int                 `toml:"keep_last"`     // finished workers kept per directory
+}
+
+// WorkerConfig controls how workers are scheduled and where they run.
+// Zero limits mean unlimited.
+type WorkerConfig struct {
+	MaxConcurrent       int      `toml:"max_concurrent"`         // running workers overall
+	MaxConcurrentPerDir int      `toml:"max_concurrent_per_dir"` // running workers per directory
+	Isolation           string   `toml:"isolation"`              // "" or "worktree"
+	DefaultTimeout      Duration `toml:"default_timeout"`        // 0 = no timeout
+	KillGrace           Duration `toml:"kill_grace"`             // SIGTERM to SIGKILL
+	Retries             int      `toml:"retries"`                // re-runs after a failed attempt
+	RetryBackoff        Duration `toml:"retry_backoff"`          // before the first retry, doubling
+}
+
+// RunnerConfig defines an agent CLI that workers can run instead of claude
+// ([runners.<name>]). Each argument is a text/template over the worker:
+// {{.Task}}, {{.Dir}}, {{.ID}} and {{.SessionID}}, plus {{.Prompt}} in Resume.
+type RunnerConfig struct {
+	Command     []string `toml:"command"`     // starts the task non-interactively
+	Resume      []string `toml:"resume"`      // continues after a failure; empty: start over
+	Interactive []string `toml:"interactive"` // for "ccl resume"; empty: unsupported
+	Format      string   `toml:"format"`      // output format: "plain" (default) or "ndjson"
+}
+
+// ProfileConfig is a named set of overrides ([profiles.<name>]) that a
+// worker can be created with. Unset keys keep the top-level values; Env is
+// merged over [claude.env] and each hook list that is set replaces its
+// [hooks] counterpart.
+type ProfileConfig struct {
+	SkipPermissions *bool             `toml:"skip_permissions"`
+	SystemPrompt    *string           `toml:"system_prompt"`
+	ExtraFlags      []string          `toml:"extra_flags"`
+	Env             map[string]string `toml:"env"`
+	Hooks           HooksConfig       `toml:"hooks"`
 }
 
 type Config struct {
-	Claude ClaudeConfig `toml:"claude"`
-	Hooks  HooksConfig  `toml:"hooks"`
+	Claude    ClaudeConfig             `toml:"claude"`
+	Hooks     HooksConfig              `toml:"hooks"`
+	Retention RetentionConfig          `toml:"retention"`
+	Worker    WorkerConfig             `toml:"worker"`
+	Runners   map[string]RunnerConfig  `toml:"runners"`
+	Profiles  map[string]ProfileConfig `toml:"profiles"`
+
+	path    string            // user config file, for ForDir
+	sources map[string]string // dotted key -> file that set it
+	ignored []string          // user-only keys found in project files
 }
 
+// ProjectFile is the name of project-local config files.
+const ProjectFile = ".ccl.toml"
+
 const defaultSystemPrompt = `You are the user's trusted programmer. Do not ask questions. Complete the entire task before stopping. If you encounter issues, debug and fix them. When finished, end with a 1-2 sentence summary.`
 
 func Defaults() *Config {
@@ -33,20 +127,268 @@ func Defaults() *Config {
 			SkipPermissions: true,
 			SystemPrompt:    defaultSystemPrompt,
 		},
+		Worker: WorkerConfig{
+			KillGrace:    Duration{10 * time.Second},
+			RetryBackoff: Duration{30 * time.Second},
+		},
 	}
 }
 
+// Load reads the user config at path. A missing file yields the defaults.
 func Load(path string) (*Config, error) {
+	return LoadFor(path, "")
+}
+
+// LoadFor reads the user config at path and merges the project files for
+// dir (see ProjectFiles) over it, the one closest to dir last. Tables,
+// [profiles.<name>] entries included, merge key by key; arrays are
+// replaced. Settings that span projects or that a repository shouldn't
+// control (see userOnly) are only read from the user config; project files
+// that set them have those keys ignored (see Ignored).
+func LoadFor(path, dir string) (*Config, error) {
 	cfg := Defaults()
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return cfg, nil
-		}
+	cfg.path = path
+	cfg.sources = map[string]string{}
+	if err := cfg.merge(path, false); err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
-	if _, err := toml.Decode(string(data), cfg); err != nil {
-		return nil, err
+	for _, f := range ProjectFiles(dir) {
+		if err := cfg.merge(f, true); err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
 	}
 	return cfg, nil
 }
+
+// ProjectFiles returns the .ccl.toml files in dir and its parents up to
+// the enclosing git root, outermost first. Outside a git repository only
+// dir's own .ccl.toml counts.
+func ProjectFiles(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if f := filepath.Join(d, ProjectFile); isFile(f) {
+			files = append(files, f)
+		}
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			slices.Reverse(files)
+			return files
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if f := filepath.Join(dir, ProjectFile); isFile(f) {
+		return []string{f}
+	}
+	return nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// merge decodes file over c and records it as the source of its keys. In
+// project files, user-only keys are dropped and recorded in c.ignored.
+func (c *Config) merge(file string, project bool) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	text := string(data)
+	if project {
+		if text, err = c.dropUserOnly(file, text); err != nil {
+			return err
+		}
+	}
+	profiles := maps.Clone(c.Profiles)
+	md, err := toml.Decode(text, c)
+	if err != nil {
+		return err
+	}
+	// Decoding replaced each [profiles.<name>] entry the file has; merge it
+	// over the earlier one instead.
+	for name, base := range profiles {
+		if md.IsDefined("profiles", name) {
+			c.Profiles[name] = c.Profiles[name].over(base)
+		}
+	}
+	for _, k := range md.Keys() {
+		key := k.String()
+		if len(k) == 2 && k[0] == "runners" {
+			// The entry replaced any earlier one wholesale.
+			for s := range c.sources {
+				if strings.HasPrefix(s, key+".") {
+					delete(c.sources, s)
+				}
+			}
+		}
+		c.sources[key] = file
+	}
+	return nil
+}
+
+// dropUserOnly returns the TOML text of project file with its user-only
+// keys removed, recording each one removed.
+func (c *Config) dropUserOnly(file, text string) (string, error) {
+	var tree map[string]any
+	md, err := toml.Decode(text, &tree)
+	if err != nil {
+		return "", err
+	}
+	var dropped []toml.Key
+	for _, k := range md.Keys() {
+		if !userOnly(k) || slices.ContainsFunc(dropped, func(d toml.Key) bool { return hasPrefix(k, d) }) {
+			continue
+		}
+		dropped = append(dropped, k)
+		c.ignored = append(c.ignored, fmt.Sprintf("%s: %s can only be set in the user config", file, k))
+		parent := tree
+		for _, name := range k[:len(k)-1] {
+			parent, _ = parent[name].(map[string]any)
+		}
+		delete(parent, k[len(k)-1])
+	}
+	if len(dropped) == 0 {
+		return text, nil
+	}
+	var buf strings.Builder
+	if err := toml.NewEncoder(&buf).Encode(tree); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func hasPrefix(k, prefix toml.Key) bool {
+	return len(k) >= len(prefix) && slices.Equal(k[:len(prefix)], prefix)
+}
+
+// userOnly reports whether key k may only be set in the user config:
+// settings that span projects, and anything that runs commands, passes
+// flags or environment to the agent, or relaxes its permissions, so that a
+// cloned repository can't take them over.
+func userOnly(k toml.Key) bool {
+	switch k[0] {
+	case "retention", "hooks", "runners":
+		return true
+	case "worker":
+		return len(k) > 1 && (k[1] == "max_concurrent" || k[1] == "max_concurrent_per_dir")
+	case "claude":
+		return len(k) > 1 && agentKey(k[1])
+	case "profiles":
+		return len(k) > 2 && (k[2] == "hooks" || agentKey(k[2]))
+	}
+	return false
+}
+
+func agentKey(name string) bool {
+	return name == "env" || name == "extra_flags" || name == "skip_permissions"
+}
+
+// over returns p with base's settings for the keys p leaves unset.
+func (p ProfileConfig) over(base ProfileConfig) ProfileConfig {
+	out := base
+	if p.SkipPermissions != nil {
+		out.SkipPermissions = p.SkipPermissions
+	}
+	if p.SystemPrompt != nil {
+		out.SystemPrompt = p.SystemPrompt
+	}
+	if p.ExtraFlags != nil {
+		out.ExtraFlags = p.ExtraFlags
+	}
+	if len(p.Env) > 0 {
+		out.Env = maps.Clone(base.Env)
+		if out.Env == nil {
+			out.Env = map[string]string{}
+		}
+		maps.Copy(out.Env, p.Env)
+	}
+	out.Hooks = p.Hooks.over(out.Hooks)
+	return out
+}
+
+// over returns h with base's commands for the hooks h leaves unset.
+func (h HooksConfig) over(base HooksConfig) HooksConfig {
+	out := base
+	override := func(dst *[]string, src []string) {
+		if src != nil {
+			*dst = src
+		}
+	}
+	override(&out.OnStart, h.OnStart)
+	override(&out.OnDone, h.OnDone)
+	override(&out.OnPending, h.OnPending)
+	override(&out.OnError, h.OnError)
+	override(&out.OnKill, h.OnKill)
+	override(&out.OnTimeout, h.OnTimeout)
+	return out
+}
+
+// Ignored describes the keys project files set that only the user config
+// may, and that were therefore ignored, one "file: key ..." line each.
+func (c *Config) Ignored() []string {
+	return c.ignored
+}
+
+// ForDir returns the config for workers in dir: c's user config with dir's
+// project files merged over it, or c itself if dir has none.
+func (c *Config) ForDir(dir string) (*Config, error) {
+	if len(ProjectFiles(dir)) == 0 {
+		return c, nil
+	}
+	return LoadFor(c.path, dir)
+}
+
+// Source returns the file that set the dotted key (as printed by
+// toml.Key.String), or "" if it has its default value.
+func (c *Config) Source(key string) string {
+	return c.sources[key]
+}
+
+// ProfileNames returns the configured profile names, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithProfile returns a copy of c with profile name applied. The empty name
+// returns c itself.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	out := *c
+	if p.SkipPermissions != nil {
+		out.Claude.SkipPermissions = *p.SkipPermissions
+	}
+	if p.SystemPrompt != nil {
+		out.Claude.SystemPrompt = *p.SystemPrompt
+	}
+	if p.ExtraFlags != nil {
+		out.Claude.ExtraFlags = p.ExtraFlags
+	}
+	if len(p.Env) > 0 {
+		env := make(map[string]string, len(c.Claude.Env)+len(p.Env))
+		maps.Copy(env, c.Claude.Env)
+		maps.Copy(env, p.Env)
+		out.Claude.Env = env
+	}
+	out.Hooks = p.Hooks.over(out.Hooks)
+	return &out, nil
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaults(t *testing.T) {
@@ -56,3 +58,183 @@ func TestLoadMissing(t *testing.T) {
 		t.Error("missing file should return defaults")
 	}
 }
+
+func TestLoadRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	os.WriteFile(path, []byte(`
+[retention]
+max_log_bytes = 1048576
+keep_last = 5
+
+[retention.max_age]
+done = "7d"
+error = "36h"
+`), 0644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Retention.MaxAge["done"].Duration != 7*24*time.Hour {
+		t.Errorf("done max age: %v", cfg.Retention.MaxAge["done"])
+	}
+	if cfg.Retention.MaxAge["error"].Duration != 36*time.Hour {
+		t.Errorf("error max age: %v", cfg.Retention.MaxAge["error"])
+	}
+	if cfg.Retention.MaxLogBytes != 1048576 || cfg.Retention.KeepLast != 5 {
+		t.Errorf("retention: %+v", cfg.Retention)
+	}
+}
+
+func TestWithProfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	os.WriteFile(path, []byte(`
+[claude]
+extra_flags = ["--model", "sonnet"]
+[claude.env]
+A = "1"
+B = "2"
+
+[hooks]
+on_done = ["echo done"]
+on_error = ["echo error"]
+
+[profiles.careful]
+skip_permissions = false
+extra_flags = ["--model", "opus"]
+[profiles.careful.env]
+B = "3"
+[profiles.careful.hooks]
+on_done = ["echo careful"]
+`), 0644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	p, err := cfg.WithProfile("careful")
+	if err != nil {
+		t.Fatalf("WithProfile: %v", err)
+	}
+	if p.Claude.SkipPermissions || p.Claude.SystemPrompt != cfg.Claude.SystemPrompt {
+		t.Errorf("expected only skip_permissions overridden, got %+v", p.Claude)
+	}
+	if p.Claude.ExtraFlags[1] != "opus" || p.Claude.Env["A"] != "1" || p.Claude.Env["B"] != "3" {
+		t.Errorf("unexpected flags/env: %v %v", p.Claude.ExtraFlags, p.Claude.Env)
+	}
+	if p.Hooks.OnDone[0] != "echo careful" || p.Hooks.OnError[0] != "echo error" {
+		t.Errorf("unexpected hooks: %+v", p.Hooks)
+	}
+	if cfg.Claude.Env["B"] != "2" || !cfg.Claude.SkipPermissions {
+		t.Error("applying a profile must not change the base config")
+	}
+	if _, err := cfg.WithProfile("nope"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
+
+func TestLoadForProjectFiles(t *testing.T) {
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, ".git"), 0755)
+	sub := filepath.Join(root, "pkg", "api")
+	os.MkdirAll(sub, 0755)
+	user := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(user, []byte("[claude]\nskip_permissions = false\nsystem_prompt = \"user\"\n[worker]\nretries = 3\n"), 0644)
+	os.WriteFile(filepath.Join(root, ".ccl.toml"), []byte("[claude]\nsystem_prompt = \"root\"\n[worker]\ndefault_timeout = \"2h\"\nretries = 1\n"), 0644)
+	os.WriteFile(filepath.Join(sub, ".ccl.toml"), []byte("[claude]\nsystem_prompt = \"api\"\n[worker]\nretries = 2\n"), 0644)
+
+	cfg, err := LoadFor(user, sub)
+	if err != nil {
+		t.Fatalf("LoadFor: %v", err)
+	}
+	if cfg.Claude.SkipPermissions || cfg.Claude.SystemPrompt != "api" {
+		t.Errorf("expected the closest file to win over the root and user config, got %+v", cfg.Claude)
+	}
+	if cfg.Worker.DefaultTimeout.Duration != 2*time.Hour || cfg.Worker.Retries != 2 {
+		t.Errorf("expected [worker] merged key by key, got %+v", cfg.Worker)
+	}
+	for key, want := range map[string]string{
+		"claude.system_prompt":    filepath.Join(sub, ".ccl.toml"),
+		"worker.default_timeout":  filepath.Join(root, ".ccl.toml"),
+		"claude.skip_permissions": user,
+		"worker.kill_grace":       "",
+	} {
+		if got := cfg.Source(key); got != want {
+			t.Errorf("Source(%s) = %q, want %q", key, got, want)
+		}
+	}
+
+	// Outside the repository only the directory itself counts.
+	if files := ProjectFiles(filepath.Dir(root)); len(files) != 0 {
+		t.Errorf("expected no project files above the git root, got %v", files)
+	}
+
+	for _, data := range []string{
+		"[worker]\nmax_concurrent = 8\n",
+		"[retention]\nkeep_last = 1\n",
+		"[hooks]\non_done = [\"curl example.com\"]\n",
+		"[runners.sh]\ncommand = [\"sh\", \"-c\", \"{{.Task}}\"]\n",
+		"[claude]\nskip_permissions = true\n",
+		"[claude]\nextra_flags = [\"--dangerously-skip-permissions\"]\n",
+		"[claude.env]\nPATH = \"/tmp\"\n",
+		"[profiles.x]\nsystem_prompt = \"ok\"\n[profiles.x.hooks]\non_start = [\"rm -rf ~\"]\n",
+		"[profiles.x.env]\nA = \"b\"\n",
+	} {
+		os.WriteFile(filepath.Join(sub, ".ccl.toml"), []byte(data), 0644)
+		cfg, err := LoadFor(user, sub)
+		if err != nil {
+			t.Errorf("LoadFor: %v", err)
+			continue
+		}
+		if ignored := cfg.Ignored(); len(ignored) != 1 || !strings.HasPrefix(ignored[0], filepath.Join(sub, ".ccl.toml")+": ") {
+			t.Errorf("expected one key ignored in:\n%s\ngot %q", data, ignored)
+		}
+		if cfg.Worker.MaxConcurrent != 0 || cfg.Retention.KeepLast != 0 || len(cfg.Hooks.OnDone) != 0 || len(cfg.Runners) != 0 ||
+			cfg.Claude.SkipPermissions || len(cfg.Claude.ExtraFlags) != 0 || len(cfg.Claude.Env) != 0 {
+			t.Errorf("expected the user-only keys in:\n%s\nto be ignored, got %+v", data, cfg)
+		}
+		if p, ok := cfg.Profiles["x"]; ok && (len(p.Hooks.OnStart) != 0 || len(p.Env) != 0) {
+			t.Errorf("expected the profile's user-only keys ignored, got %+v", p)
+		}
+	}
+	os.WriteFile(filepath.Join(sub, ".ccl.toml"), []byte("[profiles.x]\nsystem_prompt = \"ok\"\n"), 0644)
+	if cfg, err := LoadFor(user, sub); err != nil || len(cfg.Ignored()) != 0 || *cfg.Profiles["x"].SystemPrompt != "ok" {
+		t.Errorf("expected a project profile with a system prompt to load: %v", err)
+	}
+}
+
+func TestLoadForProjectProfileMergesOverUser(t *testing.T) {
+	project := t.TempDir()
+	user := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(user, []byte(`[claude]
+skip_permissions = true
+
+[profiles.safe]
+skip_permissions = false
+extra_flags = ["--model", "opus"]
+
+[profiles.safe.hooks]
+on_done = ["notify-send done"]
+`), 0644)
+	os.WriteFile(filepath.Join(project, ".ccl.toml"), []byte("[profiles.safe]\nsystem_prompt = \"hello\"\n"), 0644)
+
+	cfg, err := LoadFor(user, project)
+	if err != nil {
+		t.Fatalf("LoadFor: %v", err)
+	}
+	p, err := cfg.WithProfile("safe")
+	if err != nil {
+		t.Fatalf("WithProfile: %v", err)
+	}
+	if p.Claude.SkipPermissions || len(p.Claude.ExtraFlags) != 2 || len(p.Hooks.OnDone) != 1 {
+		t.Errorf("expected the user's profile settings kept, got %+v %+v", p.Claude, p.Hooks)
+	}
+	if p.Claude.SystemPrompt != "hello" {
+		t.Errorf("expected the project's system prompt, got %q", p.Claude.SystemPrompt)
+	}
+	if got := cfg.Source("profiles.safe.skip_permissions"); got != user {
+		t.Errorf("Source(profiles.safe.skip_permissions) = %q, want %q", got, user)
+	}
+}
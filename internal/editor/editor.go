@@ -0,0 +1,78 @@
+// Package editor lets the user write text in their own editor.
+package editor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Scissors separates the text being edited from the help below it, which is
+// dropped when the file is read back.
+const Scissors = "# ------------------------ >8 ------------------------"
+
+// Command returns the command that opens path in $VISUAL, $EDITOR or vi,
+// attached to the terminal. It runs through sh so the variables can carry
+// arguments, e.g. EDITOR="code --wait".
+func Command(path string) *exec.Cmd {
+	ed := os.Getenv("VISUAL")
+	if ed == "" {
+		ed = os.Getenv("EDITOR")
+	}
+	if ed == "" {
+		ed = "vi"
+	}
+	cmd := exec.Command("sh", "-c", ed+` "$1"`, "sh", path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd
+}
+
+// WriteTemp writes text to a new temporary Markdown file, followed by the
+// scissors line and help as comments if help is set, and returns its path.
+func WriteTemp(text, help string) (string, error) {
+	var b strings.Builder
+	b.WriteString(text)
+	if help != "" {
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n" + Scissors + "\n")
+		for _, line := range strings.Split(strings.TrimRight(help, "\n"), "\n") {
+			b.WriteString("# " + line + "\n")
+		}
+	}
+	f, err := os.CreateTemp("", "ccl-task-*.md")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// Read returns the contents of path up to the scissors line, trimmed.
+func Read(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	text, _, _ := strings.Cut(string(data), Scissors)
+	return strings.TrimSpace(text), nil
+}
+
+// Edit opens text in the user's editor and returns what they saved.
+func Edit(text, help string) (string, error) {
+	path, err := WriteTemp(text, help)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(path)
+	if err := Command(path).Run(); err != nil {
+		return "", fmt.Errorf("editor: %w", err)
+	}
+	return Read(path)
+}
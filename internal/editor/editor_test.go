@@ -0,0 +1,40 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	// The "editor" appends a line above the scissors and checks it was given
+	// the template.
+	script := filepath.Join(t.TempDir(), "ed")
+	os.WriteFile(script, []byte("#!/bin/sh\ngrep -q '^# write it here$' \"$1\" || exit 1\nsed -i '1a and then test it' \"$1\"\n"), 0755)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", script)
+
+	got, err := Edit("# Fix the bug", "write it here")
+	if err != nil {
+		t.Fatalf("Edit: %v", err)
+	}
+	if got != "# Fix the bug\nand then test it" {
+		t.Errorf("unexpected text %q", got)
+	}
+}
+
+func TestReadDropsHelp(t *testing.T) {
+	path, err := WriteTemp("task\n", "help line 1\nhelp line 2")
+	if err != nil {
+		t.Fatalf("WriteTemp: %v", err)
+	}
+	defer os.Remove(path)
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), Scissors+"\n# help line 1\n# help line 2\n") {
+		t.Errorf("unexpected template:\n%s", data)
+	}
+	if got, _ := Read(path); got != "task" {
+		t.Errorf("expected only the text above the scissors, got %q", got)
+	}
+}
@@ -0,0 +1,178 @@
+// Package git wraps the few git commands ccl needs.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// run executes git in dir and returns its trimmed stdout. Failures include
+// git's stderr.
+func run(dir string, args ...string) (string, error) {
+	return runEnv(dir, nil, args...)
+}
+
+func runEnv(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Root returns the top-level directory of the repository containing dir.
+func Root(dir string) (string, error) {
+	return run(dir, "rev-parse", "--show-toplevel")
+}
+
+// Head returns the commit HEAD points to in dir.
+func Head(dir string) (string, error) {
+	return run(dir, "rev-parse", "HEAD")
+}
+
+// AddWorktree checks out a new branch at commit into a worktree at path.
+func AddWorktree(repo, path, branch, commit string) error {
+	_, err := run(repo, "worktree", "add", "-b", branch, path, commit)
+	return err
+}
+
+// RemoveWorktree removes the worktree at path, leaving its branch. Without
+// force git refuses if the worktree has uncommitted changes.
+func RemoveWorktree(repo, path string, force bool) error {
+	args := []string{"worktree", "remove"}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err := run(repo, append(args, path)...)
+	return err
+}
+
+// PruneWorktrees forgets worktrees whose directories no longer exist.
+func PruneWorktrees(repo string) error {
+	_, err := run(repo, "worktree", "prune")
+	return err
+}
+
+// Branch returns the name of the branch checked out in dir, or "HEAD" if it
+// is detached.
+func Branch(dir string) (string, error) {
+	return run(dir, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// Dirty reports whether dir has uncommitted or untracked changes.
+func Dirty(dir string) (bool, error) {
+	out, err := run(dir, "status", "--porcelain")
+	return out != "", err
+}
+
+// Snapshot returns a commit holding the tracked, uncommitted state of dir
+// without touching the working tree or index, or "" if there is nothing
+// uncommitted. The commit is unreferenced; see UpdateRef.
+func Snapshot(dir string) (string, error) {
+	// The snapshot's author is irrelevant; don't fail on missing identity.
+	return runEnv(dir, []string{
+		"GIT_AUTHOR_NAME=ccl", "GIT_AUTHOR_EMAIL=ccl@localhost",
+		"GIT_COMMITTER_NAME=ccl", "GIT_COMMITTER_EMAIL=ccl@localhost",
+	}, "stash", "create")
+}
+
+// UpdateRef points ref at commit, which keeps git gc from pruning it.
+func UpdateRef(dir, ref, commit string) error {
+	_, err := run(dir, "update-ref", ref, commit)
+	return err
+}
+
+// DeleteRef deletes ref. A ref that doesn't exist is not an error.
+func DeleteRef(dir, ref string) error {
+	_, err := run(dir, "update-ref", "-d", ref)
+	return err
+}
+
+// Commits returns "<abbrev> <subject>" for each commit in from..to, oldest
+// first.
+func Commits(dir, from, to string) ([]string, error) {
+	out, err := run(dir, "log", "--reverse", "--format=%h %s", from+".."+to)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// FileStat is one file's line counts in a diff. Binary files count -1.
+type FileStat struct {
+	Path    string `json:"path"`
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+}
+
+// NumStat returns per-file changes between from and to. An empty to
+// compares against the working tree.
+func NumStat(dir, from, to string) ([]FileStat, error) {
+	args := []string{"diff", "--numstat", from}
+	if to != "" {
+		args = append(args, to)
+	}
+	out, err := run(dir, args...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	var stats []FileStat
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		fs := FileStat{Path: fields[2], Added: -1, Deleted: -1}
+		if n, err := strconv.Atoi(fields[0]); err == nil {
+			fs.Added = n
+		}
+		if n, err := strconv.Atoi(fields[1]); err == nil {
+			fs.Deleted = n
+		}
+		stats = append(stats, fs)
+	}
+	return stats, nil
+}
+
+// Untracked lists files in dir that are neither tracked nor ignored.
+func Untracked(dir string) ([]string, error) {
+	out, err := run(dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Diff returns the diff between from and to (the working tree if to is
+// empty). With stat set it returns a diffstat instead of a patch.
+func Diff(dir, from, to string, stat bool) (string, error) {
+	args := []string{"diff"}
+	if stat {
+		args = append(args, "--stat")
+	}
+	args = append(args, from)
+	if to != "" {
+		args = append(args, to)
+	}
+	return run(dir, args...)
+}
+
+// FormatPatch returns the commits in from..to as an mbox of patches, as
+// produced by git format-patch --stdout.
+func FormatPatch(dir, from, to string) (string, error) {
+	return run(dir, "format-patch", "--stdout", from+".."+to)
+}
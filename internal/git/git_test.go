@@ -0,0 +1,56 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a repository with a single commit.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestWorktreeLifecycle(t *testing.T) {
+	repo := initRepo(t)
+	head, err := Head(repo)
+	if err != nil || len(head) != 40 {
+		t.Fatalf("Head = %q, %v", head, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "wt")
+	if err := AddWorktree(repo, path, "ccl/test", head); err != nil {
+		t.Fatalf("AddWorktree: %v", err)
+	}
+	if root, _ := Root(path); root != path {
+		t.Errorf("Root(worktree) = %q, want %q", root, path)
+	}
+
+	os.WriteFile(filepath.Join(path, "dirty.txt"), []byte("x"), 0644)
+	if err := RemoveWorktree(repo, path, false); err == nil {
+		t.Fatal("expected dirty worktree removal to fail")
+	} else if !strings.Contains(err.Error(), "git worktree") {
+		t.Errorf("error should name the git command: %v", err)
+	}
+	if err := RemoveWorktree(repo, path, true); err != nil {
+		t.Fatalf("forced RemoveWorktree: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("worktree directory should be gone")
+	}
+	if _, err := run(repo, "rev-parse", "--verify", "ccl/test"); err != nil {
+		t.Error("branch should survive worktree removal")
+	}
+}
@@ -8,12 +8,34 @@ import (
 	"text/template"
 )
 
+// Result is the outcome of a single hook command.
+type Result struct {
+	Cmd string
+	Err error
+}
+
 type Vars struct {
 	ID        string
 	Task      string
 	Dir       string
 	Status    string
 	SessionID string
+
+	// Set once claude has exited; empty otherwise.
+	ExitCode      string
+	Signal        string
+	ErrorReason   string
+	ResultSubtype string
+
+	// Metrics from claude's result event; zero if it never reported one.
+	CostUSD      float64
+	NumTurns     int
+	InputTokens  int64
+	OutputTokens int64
+	DurationMS   int64
+
+	// Attempt is the 1-based attempt the worker is on or finished with.
+	Attempt int
 }
 
 func render(tmpl string, vars Vars) (string, error) {
@@ -29,24 +51,30 @@ func render(tmpl string, vars Vars) (string, error) {
 }
 
 // Fire executes hook commands concurrently and waits for all of them to
-// start before returning. Each command is a shell string run via sh -c.
+// finish before returning. Each command is a shell string run via sh -c.
 // Template variables are expanded before execution. Failures are logged
-// but don't propagate.
-func Fire(cmds []string, vars Vars) {
+// and reported in the returned results, one per command.
+func Fire(cmds []string, vars Vars) []Result {
+	results := make([]Result, len(cmds))
 	var wg sync.WaitGroup
-	for _, cmdTmpl := range cmds {
+	for i, cmdTmpl := range cmds {
+		results[i].Cmd = cmdTmpl
 		expanded, err := render(cmdTmpl, vars)
 		if err != nil {
 			log.Printf("hook template error: %v", err)
+			results[i].Err = err
 			continue
 		}
+		results[i].Cmd = expanded
 		wg.Add(1)
-		go func(cmd string) {
+		go func(i int, cmd string) {
 			defer wg.Done()
 			if err := exec.Command("sh", "-c", cmd).Run(); err != nil {
 				log.Printf("hook failed: %s: %v", cmd, err)
+				results[i].Err = err
 			}
-		}(expanded)
+		}(i, expanded)
 	}
 	wg.Wait()
+	return results
 }
@@ -52,3 +52,19 @@ func TestFireWithTemplateVars(t *testing.T) {
 		t.Errorf("unexpected: %q", got)
 	}
 }
+
+func TestFireReportsResults(t *testing.T) {
+	results := Fire([]string{"true", "exit 3", "{{.Bogus"}, Vars{ID: "1"})
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Err != nil {
+		t.Errorf("expected success: %v", results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Error("expected failure for non-zero exit")
+	}
+	if results[2].Err == nil {
+		t.Error("expected template error")
+	}
+}
@@ -18,9 +18,55 @@ const (
 // Event is a single parsed log event.
 type Event struct {
 	Type     EventType
-	Text     string // for EventText
-	ToolName string // for EventTool
-	SubType  string // for EventResult
+	Text     string  // for EventText
+	ToolName string  // for EventTool
+	SubType  string  // for EventResult
+	Result   *Result // for EventResult
+}
+
+// Result holds the metrics claude reports in its final result event.
+type Result struct {
+	CostUSD    float64 `json:"total_cost_usd"`
+	NumTurns   int     `json:"num_turns"`
+	DurationMS int64   `json:"duration_ms"`
+	Usage      Usage   `json:"usage"`
+}
+
+// Usage is the token usage reported in a result event.
+type Usage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+}
+
+// Log formats a worker's output can be in.
+const (
+	FormatStreamJSON = "stream-json" // claude's; the default
+	FormatNDJSON     = "ndjson"      // stream-json shaped lines from other agents
+	FormatPlain      = "plain"       // one line of text per line
+)
+
+// ValidFormat reports whether format is a known log format. Empty means
+// stream-json.
+func ValidFormat(format string) bool {
+	switch format {
+	case "", FormatStreamJSON, FormatNDJSON, FormatPlain:
+		return true
+	}
+	return false
+}
+
+// Parser returns the line parser for a log format.
+func Parser(format string) func(line []byte) []Event {
+	if format == FormatPlain {
+		return parsePlain
+	}
+	return ParseLine
+}
+
+func parsePlain(line []byte) []Event {
+	return []Event{{Type: EventText, Text: string(line)}}
 }
 
 // ParseLine parses an NDJSON log line into zero or more events.
@@ -45,7 +91,9 @@ func ParseLine(line []byte) []Event {
 		return []Event{{Type: EventTool, ToolName: name}}
 	case "result":
 		sub, _ := raw["subtype"].(string)
-		return []Event{{Type: EventResult, SubType: sub}}
+		var r Result
+		json.Unmarshal(line, &r)
+		return []Event{{Type: EventResult, SubType: sub, Result: &r}}
 	default:
 		return nil
 	}
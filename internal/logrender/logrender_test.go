@@ -54,6 +54,21 @@ func TestParseResult(t *testing.T) {
 	}
 }
 
+func TestParseResultMetrics(t *testing.T) {
+	line := `{"type":"result","subtype":"success","total_cost_usd":0.0421,"num_turns":7,"duration_ms":83000,"usage":{"input_tokens":1200,"output_tokens":3400,"cache_creation_input_tokens":500,"cache_read_input_tokens":9000}}`
+	events := ParseLine([]byte(line))
+	if len(events) != 1 || events[0].Result == nil {
+		t.Fatalf("expected result with metrics, got %+v", events)
+	}
+	r := events[0].Result
+	if r.CostUSD != 0.0421 || r.NumTurns != 7 || r.DurationMS != 83000 {
+		t.Errorf("unexpected metrics: %+v", r)
+	}
+	if r.Usage.InputTokens != 1200 || r.Usage.OutputTokens != 3400 || r.Usage.CacheCreationInputTokens != 500 || r.Usage.CacheReadInputTokens != 9000 {
+		t.Errorf("unexpected usage: %+v", r.Usage)
+	}
+}
+
 func TestParseSystem(t *testing.T) {
 	line := `{"type":"system","subtype":"init"}`
 	events := ParseLine([]byte(line))
@@ -89,3 +104,14 @@ func TestRenderPlain(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, out)
 	}
 }
+
+func TestParserPlain(t *testing.T) {
+	line := []byte(`{"type":"assistant","content":"not parsed"}`)
+	events := Parser(FormatPlain)(line)
+	if len(events) != 1 || events[0].Type != EventText || events[0].Text != string(line) {
+		t.Errorf("plain format should keep lines verbatim, got %+v", events)
+	}
+	if events := Parser("")(line); len(events) != 1 || events[0].Text != "not parsed" {
+		t.Errorf("default format should parse stream-json, got %+v", events)
+	}
+}
@@ -0,0 +1,233 @@
+package state
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveDirName is the subdirectory of the state dir holding archived
+// workers, grouped by the month they finished in (archive/YYYY-MM/).
+const ArchiveDirName = "archive"
+
+func archiveRoot(dir string) string {
+	return filepath.Join(dir, ArchiveDirName)
+}
+
+// archivedDir returns the month directory holding worker id, or "" if it
+// isn't archived.
+func archivedDir(dir, id string) string {
+	matches, _ := filepath.Glob(filepath.Join(archiveRoot(dir), "*", id+".json"))
+	if len(matches) == 0 {
+		return ""
+	}
+	return filepath.Dir(matches[0])
+}
+
+func archiveMonth(w *Worker) string {
+	t := time.Now()
+	if w.FinishedAt != nil {
+		t = *w.FinishedAt
+	} else if w.CreatedAt != nil {
+		t = *w.CreatedAt
+	}
+	return t.Format("2006-01")
+}
+
+// Archive moves worker id into the archive if its status is one of from (any
+// status if from is empty). The state file and journal are moved as-is and
+// the log is gzip-compressed. The archived worker is returned.
+func Archive(dir, id string, from []Status) (*Worker, error) {
+	unlock, err := lock(dir, id)
+	if err != nil {
+		return nil, fmt.Errorf("lock worker %s: %w", id, err)
+	}
+	defer unlock()
+
+	w, err := Read(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	if !statusIn(w.Status, from) {
+		return w, fmt.Errorf("worker %s is %s, not %s", id, w.Status, joinStatuses(from))
+	}
+
+	dest := filepath.Join(archiveRoot(dir), archiveMonth(w))
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return w, err
+	}
+	if err := compressFile(filepath.Join(dir, id+".log"), filepath.Join(dest, id+".log.gz")); err != nil {
+		return w, fmt.Errorf("archive log: %w", err)
+	}
+	if err := os.Rename(journalPath(dir, id), journalPath(dest, id)); err != nil && !os.IsNotExist(err) {
+		return w, fmt.Errorf("archive journal: %w", err)
+	}
+	if err := os.Rename(taskPath(dir, id), taskPath(dest, id)); err != nil && !os.IsNotExist(err) {
+		return w, fmt.Errorf("archive task: %w", err)
+	}
+	w.Archived = true
+	if err := Write(dest, w); err != nil {
+		return w, err
+	}
+	os.Remove(filepath.Join(dir, id+".log"))
+	os.Remove(lockPath(dir, id))
+	return w, os.Remove(statePath(dir, id))
+}
+
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ListArchived returns all archived workers, oldest first.
+func ListArchived(dir string) ([]*Worker, error) {
+	matches, err := filepath.Glob(filepath.Join(archiveRoot(dir), "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var workers []*Worker
+	for _, m := range matches {
+		w, err := Read(filepath.Dir(m), strings.TrimSuffix(filepath.Base(m), ".json"))
+		if err != nil {
+			continue
+		}
+		w.Archived = true
+		workers = append(workers, w)
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		return createdBefore(workers[i], workers[j])
+	})
+	return workers, nil
+}
+
+// ListAll returns live workers followed by archived ones.
+func ListAll(dir string) ([]*Worker, error) {
+	live, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := ListArchived(dir)
+	if err != nil {
+		return nil, err
+	}
+	return append(live, archived...), nil
+}
+
+// ReadAny reads worker id from the live state dir, falling back to the
+// archive.
+func ReadAny(dir, id string) (*Worker, error) {
+	w, err := Read(dir, id)
+	if err == nil {
+		return w, nil
+	}
+	if adir := archivedDir(dir, id); adir != "" {
+		w, err := Read(adir, id)
+		if err != nil {
+			return nil, err
+		}
+		w.Archived = true
+		return w, nil
+	}
+	return nil, err
+}
+
+// ResolveAny is like Resolve but also matches archived workers.
+func ResolveAny(dir, ref string) (string, error) {
+	workers, err := ListAll(dir)
+	if err != nil {
+		return "", err
+	}
+	return resolveIn(workers, ref)
+}
+
+// OpenLog opens the log of worker id, transparently decompressing it if the
+// worker is archived.
+func OpenLog(dir, id string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(dir, id+".log"))
+	if err == nil || !os.IsNotExist(err) {
+		return f, err
+	}
+	adir := archivedDir(dir, id)
+	if adir == "" {
+		return nil, err
+	}
+	gz, err := os.Open(filepath.Join(adir, id+".log.gz"))
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(gz)
+	if err != nil {
+		gz.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, gz}, nil
+}
+
+// ReadLog returns the full log of worker id, live or archived.
+func ReadLog(dir, id string) ([]byte, error) {
+	r, err := OpenLog(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, r)
+	return buf.Bytes(), err
+}
+
+// Purge permanently deletes worker id, whether live or archived, under its
+// lock if its status is one of from (any status if from is empty).
+func Purge(dir, id string, from []Status) error {
+	unlock, err := lock(dir, id)
+	if err != nil {
+		return fmt.Errorf("lock worker %s: %w", id, err)
+	}
+	defer unlock()
+
+	w, err := ReadAny(dir, id)
+	if err != nil {
+		return fmt.Errorf("worker %s not found", id)
+	}
+	if !statusIn(w.Status, from) {
+		return fmt.Errorf("worker %s is %s, not %s", id, w.Status, joinStatuses(from))
+	}
+	if !w.Archived {
+		return Delete(dir, id)
+	}
+	adir := archivedDir(dir, id)
+	os.Remove(filepath.Join(adir, id+".log.gz"))
+	os.Remove(journalPath(adir, id))
+	os.Remove(taskPath(adir, id))
+	os.Remove(lockPath(dir, id))
+	return os.Remove(statePath(adir, id))
+}
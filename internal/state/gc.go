@@ -0,0 +1,136 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Policy describes which finished workers may be removed from the state dir.
+// Zero values disable the corresponding rule.
+type Policy struct {
+	MaxAge      map[Status]time.Duration
+	MaxLogBytes int64
+	KeepLast    int
+}
+
+// Removal is a worker selected for removal by PlanGC, and why.
+type Removal struct {
+	Worker *Worker
+	Reason string
+}
+
+// PlanGC returns the finished workers, live and archived, that policy p says
+// should be removed. Rules apply in order: max age per status, keep the
+// newest KeepLast per directory, then drop the oldest until the total log
+// size fits MaxLogBytes. Workers that an unfinished worker still lists in
+// After are never removed, nor are the workers in keep, though both count
+// towards KeepLast and MaxLogBytes. Nothing is deleted.
+func PlanGC(dir string, p Policy, now time.Time, keep ...string) ([]Removal, error) {
+	all, err := ListAll(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	needed := map[string]bool{}
+	for _, id := range keep {
+		needed[id] = true
+	}
+	for _, w := range all {
+		if !w.Status.Finished() {
+			for _, id := range w.After {
+				needed[id] = true
+			}
+		}
+	}
+
+	var candidates []*Worker
+	for _, w := range all {
+		if w.Status.Finished() {
+			candidates = append(candidates, w)
+		}
+	}
+	// Newest first; the rules below keep from the front and drop from the back.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return finishedTime(candidates[j]).Before(finishedTime(candidates[i]))
+	})
+
+	var removals []Removal
+	removed := map[string]bool{}
+	drop := func(w *Worker, reason string) {
+		if needed[w.ID] {
+			return
+		}
+		removed[w.ID] = true
+		removals = append(removals, Removal{Worker: w, Reason: reason})
+	}
+
+	for _, w := range candidates {
+		if maxAge := p.MaxAge[w.Status]; maxAge > 0 {
+			if age := now.Sub(finishedTime(w)); age > maxAge {
+				drop(w, fmt.Sprintf("%s for %s, max age %s", w.Status, age.Round(time.Minute), maxAge))
+			}
+		}
+	}
+
+	if p.KeepLast > 0 {
+		kept := map[string]int{}
+		for _, w := range candidates {
+			if removed[w.ID] {
+				continue
+			}
+			kept[w.Directory]++
+			if kept[w.Directory] > p.KeepLast {
+				drop(w, fmt.Sprintf("more than %d finished workers in %s", p.KeepLast, w.Directory))
+			}
+		}
+	}
+
+	if p.MaxLogBytes > 0 {
+		var total int64
+		for _, w := range all {
+			if !removed[w.ID] {
+				total += LogSize(dir, w)
+			}
+		}
+		for i := len(candidates) - 1; i >= 0 && total > p.MaxLogBytes; i-- {
+			w := candidates[i]
+			if removed[w.ID] || needed[w.ID] {
+				continue
+			}
+			size := LogSize(dir, w)
+			if size == 0 {
+				continue
+			}
+			drop(w, fmt.Sprintf("logs total %d bytes, max %d", total, p.MaxLogBytes))
+			total -= size
+		}
+	}
+
+	return removals, nil
+}
+
+// LogSize returns the on-disk size of w's log, compressed if archived.
+func LogSize(dir string, w *Worker) int64 {
+	path := filepath.Join(dir, w.ID+".log")
+	if w.Archived {
+		path = filepath.Join(archivedDir(dir, w.ID), w.ID+".log.gz")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func finishedTime(w *Worker) time.Time {
+	switch {
+	case w.FinishedAt != nil:
+		return *w.FinishedAt
+	case w.CreatedAt != nil:
+		return *w.CreatedAt
+	}
+	return time.Time{}
+}
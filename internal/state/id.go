@@ -0,0 +1,124 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LastAlias resolves to the most recently created worker.
+const LastAlias = "last"
+
+const idAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// NewID returns a worker ID that sorts by creation time: the unix
+// milliseconds in base36 followed by a four character random suffix.
+func NewID(t time.Time) string {
+	// Bytes from the top of the range, where 256 isn't a multiple of the
+	// alphabet, are skipped so that every character is equally likely.
+	limit := 256 - 256%len(idAlphabet)
+	suffix := make([]byte, 0, 4)
+	var buf [8]byte
+	for len(suffix) < cap(suffix) {
+		rand.Read(buf[:])
+		for _, b := range buf {
+			if int(b) < limit && len(suffix) < cap(suffix) {
+				suffix = append(suffix, idAlphabet[int(b)%len(idAlphabet)])
+			}
+		}
+	}
+	return strconv.FormatInt(t.UnixMilli(), 36) + string(suffix)
+}
+
+// Create assigns w a fresh ID and writes its state file. The file is created
+// with O_EXCL so concurrent callers can never overwrite each other. A task
+// longer than TaskInlineMax is moved to a task file; see ReadTask.
+func Create(dir string, w *Worker) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	now := time.Now()
+	if w.CreatedAt == nil {
+		w.CreatedAt = &now
+	}
+	w.SchemaVersion = SchemaVersion
+	for attempt := 0; attempt < 10; attempt++ {
+		w.ID = NewID(*w.CreatedAt)
+		f, err := os.OpenFile(statePath(dir, w.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// The ID is ours now, so a long task can go in a file named after it.
+		err = storeTask(dir, w)
+		var data []byte
+		if err == nil {
+			data, err = json.MarshalIndent(w, "", "  ")
+		}
+		if err == nil {
+			_, err = f.Write(data)
+		}
+		if err != nil {
+			f.Close()
+			os.Remove(statePath(dir, w.ID))
+			os.Remove(taskPath(dir, w.ID))
+			return err
+		}
+		return f.Close()
+	}
+	return fmt.Errorf("could not allocate a unique worker ID")
+}
+
+// Resolve expands ref to a full worker ID. ref may be a complete ID, any
+// unique prefix of one, or LastAlias.
+func Resolve(dir, ref string) (string, error) {
+	workers, err := List(dir)
+	if err != nil {
+		return "", err
+	}
+	return resolveIn(workers, ref)
+}
+
+func resolveIn(workers []*Worker, ref string) (string, error) {
+	if ref == LastAlias {
+		if len(workers) == 0 {
+			return "", fmt.Errorf("no workers")
+		}
+		sort.SliceStable(workers, func(i, j int) bool {
+			return createdBefore(workers[i], workers[j])
+		})
+		return workers[len(workers)-1].ID, nil
+	}
+
+	var matches []string
+	for _, w := range workers {
+		if w.ID == ref {
+			return w.ID, nil
+		}
+		if strings.HasPrefix(w.ID, ref) {
+			matches = append(matches, w.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("worker %s not found", ref)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("ambiguous ID %q matches: %s", ref, strings.Join(matches, ", "))
+}
+
+func createdBefore(a, b *Worker) bool {
+	if a.CreatedAt != nil && b.CreatedAt != nil && !a.CreatedAt.Equal(*b.CreatedAt) {
+		return a.CreatedAt.Before(*b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,144 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EventType names an entry in a worker's journal.
+type EventType string
+
+const (
+	EventCreated   EventType = "created"
+	EventApproved  EventType = "approved"
+	EventScheduled EventType = "scheduled"
+	EventUnblocked EventType = "unblocked"
+	EventStarted   EventType = "started"
+	EventPID       EventType = "pid"
+	EventStale     EventType = "stale"
+	EventFinished  EventType = "finished"
+	EventKilled    EventType = "killed"
+	EventTimeout   EventType = "timeout"
+	EventRetry     EventType = "retry"
+	EventPaused    EventType = "paused"
+	EventUnpaused  EventType = "unpaused"
+	EventDenied    EventType = "denied"
+	EventResumed   EventType = "resumed"
+	EventHook      EventType = "hook"
+	EventWorktree  EventType = "worktree"
+)
+
+// Event is a single line in a worker's append-only journal.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     EventType `json:"type"`
+	Status   Status    `json:"status,omitempty"`
+	Actor    string    `json:"actor,omitempty"`
+	PID      int       `json:"pid,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Signal   string    `json:"signal,omitempty"`
+	Hook     string    `json:"hook,omitempty"`
+	Command  string    `json:"command,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+}
+
+func journalPath(dir, id string) string {
+	return filepath.Join(dir, id+".events")
+}
+
+// Actor describes who is acting, e.g. "alice (cli)".
+func Actor(via string) string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if name == "" {
+		return via
+	}
+	return fmt.Sprintf("%s (%s)", name, via)
+}
+
+// AppendEvent adds e to the journal of worker id. Time defaults to now.
+func AppendEvent(dir, id string, e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(journalPath(dir, id), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// ReadEvents returns the journal of worker id, live or archived, in the
+// order it was written. A worker without a journal has no events.
+func ReadEvents(dir, id string) ([]Event, error) {
+	f, err := os.Open(journalPath(dir, id))
+	if os.IsNotExist(err) {
+		if adir := archivedDir(dir, id); adir != "" {
+			f, err = os.Open(journalPath(adir, id))
+		}
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// Summary renders the event's details (everything but time and type) as a
+// short human-readable string.
+func (e Event) Summary() string {
+	var parts []string
+	if e.Status != "" {
+		parts = append(parts, "status="+string(e.Status))
+	}
+	if e.PID > 0 {
+		parts = append(parts, fmt.Sprintf("pid=%d", e.PID))
+	}
+	if e.ExitCode != nil {
+		parts = append(parts, fmt.Sprintf("exit=%d", *e.ExitCode))
+	}
+	if e.Signal != "" {
+		parts = append(parts, "signal="+e.Signal)
+	}
+	if e.Hook != "" {
+		parts = append(parts, e.Hook+": "+e.Command)
+	}
+	if e.Error != "" {
+		parts = append(parts, "error="+e.Error)
+	}
+	if e.Detail != "" {
+		parts = append(parts, e.Detail)
+	}
+	if e.Actor != "" {
+		parts = append(parts, "by "+e.Actor)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,115 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the state file format written by this build. Files
+// without a schema_version field are version 0.
+const SchemaVersion = 2
+
+// migrations[n] upgrades a decoded state file from version n to n+1.
+// Append to this list (and bump SchemaVersion) whenever the format changes.
+var migrations = []func(doc map[string]interface{}) error{
+	migrateV0,
+	migrateV1,
+}
+
+// migrateV0 upgrades files written before versioning. Those workers were
+// named after the unix second they were created in and may lack created_at.
+func migrateV0(doc map[string]interface{}) error {
+	if _, ok := doc["created_at"]; ok {
+		return nil
+	}
+	id, _ := doc["id"].(string)
+	if secs, err := strconv.ParseInt(id, 10, 64); err == nil {
+		doc["created_at"] = time.Unix(secs, 0).UTC().Format(time.RFC3339)
+	}
+	return nil
+}
+
+// migrateV1 turns the single image a worker could reference into its first
+// attachment.
+func migrateV1(doc map[string]interface{}) error {
+	if image, ok := doc["image"].(string); ok && image != "" {
+		doc["attachments"] = []interface{}{image}
+	}
+	delete(doc, "image")
+	return nil
+}
+
+// decode parses a state file, running any migrations needed to bring it up
+// to SchemaVersion. Files from a newer schema are decoded as-is and keep
+// their version so that Write refuses to downgrade them.
+func decode(data []byte) (*Worker, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	version := 0
+	if v, ok := doc["schema_version"].(float64); ok {
+		version = int(v)
+	}
+	for ; version < SchemaVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return nil, fmt.Errorf("migrate schema %d: %w", version, err)
+		}
+		doc["schema_version"] = version + 1
+	}
+
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var w Worker
+	if err := json.Unmarshal(upgraded, &w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func rawVersion(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var doc struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, err
+	}
+	return doc.SchemaVersion, nil
+}
+
+// Migrate rewrites every live and archived state file that is older than
+// SchemaVersion and returns the IDs it upgraded.
+func Migrate(dir string) ([]string, error) {
+	live, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	archived, _ := filepath.Glob(filepath.Join(archiveRoot(dir), "*", "*.json"))
+
+	var migrated []string
+	for _, path := range append(live, archived...) {
+		version, err := rawVersion(path)
+		if err != nil || version >= SchemaVersion {
+			continue
+		}
+		fileDir := filepath.Dir(path)
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		_, err = Transition(fileDir, id, nil, func(w *Worker) error { return nil })
+		if err != nil {
+			return migrated, err
+		}
+		if fileDir != dir {
+			os.Remove(lockPath(fileDir, id))
+		}
+		migrated = append(migrated, id)
+	}
+	return migrated, nil
+}
@@ -14,22 +14,142 @@ type Status string
 
 const (
 	StatusPending Status = "pending"
+	StatusQueued  Status = "queued"
+	StatusBlocked Status = "blocked"
 	StatusWorking Status = "working"
+	StatusPaused  Status = "paused"
 	StatusDone    Status = "done"
 	StatusError   Status = "error"
+	StatusKilled  Status = "killed"
+	StatusDenied  Status = "denied"
+	StatusTimeout Status = "timeout"
 )
 
+// FinishedStatuses are the terminal statuses a worker can end up in.
+var FinishedStatuses = []Status{StatusDone, StatusError, StatusKilled, StatusDenied, StatusTimeout}
+
+// Finished reports whether s is a terminal status.
+func (s Status) Finished() bool {
+	return statusIn(s, FinishedStatuses)
+}
+
+// In reports whether s is one of set. An empty set matches any status.
+func (s Status) In(set []Status) bool {
+	return statusIn(s, set)
+}
+
 type Worker struct {
+	SchemaVersion int `json:"schema_version"`
+
 	ID         string     `json:"id"`
 	Status     Status     `json:"status"`
 	Directory  string     `json:"directory"`
 	Task       string     `json:"task"`
-	Image      string     `json:"image,omitempty"`
 	PID        int        `json:"pid,omitempty"`
+	PGID       int        `json:"pgid,omitempty"`
+	RunnerPID  int        `json:"runner_pid,omitempty"`
 	SessionID  string     `json:"session_id,omitempty"`
+	Runner     string     `json:"runner,omitempty"`     // agent CLI; "" is claude
+	Profile    string     `json:"profile,omitempty"`    // [profiles.<name>] applied to config
+	LogFormat  string     `json:"log_format,omitempty"` // of the log, see logrender.Parser
+	Template   string     `json:"template,omitempty"`   // task template it was created from
+	Labels     []string   `json:"labels,omitempty"`
+	Priority   int        `json:"priority,omitempty"`
+	TimeoutMS  int64      `json:"timeout_ms,omitempty"`
+	Retries    int        `json:"retries,omitempty"` // extra attempts allowed after a failure
+	Attempt    int        `json:"attempt,omitempty"` // 1-based, of the current or last run
 	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	QueuedAt   *time.Time `json:"queued_at,omitempty"`
 	StartedAt  *time.Time `json:"started_at,omitempty"`
 	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// TaskFile, if set, names the file beside the state file holding the
+	// full task, and Task is a summary of it. See TaskInlineMax.
+	TaskFile string `json:"task_file,omitempty"`
+	// Attachments are files (images, PDFs, text) the agent is asked to
+	// read. Context files are read when the worker starts and their
+	// contents included in the prompt. Both hold absolute paths.
+	Attachments []string `json:"attachments,omitempty"`
+	Context     []string `json:"context,omitempty"`
+	// After lists the workers this one waits for while blocked: for all of
+	// them to finish done, or with AfterAny for one. With OnFailure it waits
+	// for them to fail (finish other than done) instead.
+	After     []string `json:"after,omitempty"`
+	AfterAny  bool     `json:"after_any,omitempty"`
+	OnFailure bool     `json:"on_failure,omitempty"`
+	// Env is added to the agent's environment. Secrets belong in EnvFiles,
+	// which are read when the worker starts and never copied into state.
+	Env      map[string]string `json:"env,omitempty"`
+	EnvFiles []string          `json:"env_files,omitempty"`
+	// PausedAt is when the current pause began; PausedMS adds up the
+	// pauses that have ended.
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+	PausedMS int64      `json:"paused_ms,omitempty"`
+	// StopRequestedAt is set by "ccl kill" while a working worker's
+	// processes are being stopped; its runner then finishes it as killed.
+	StopRequestedAt *time.Time `json:"stop_requested_at,omitempty"`
+	StoppedBy       string     `json:"stopped_by,omitempty"`
+	KillSignal      string     `json:"kill_signal,omitempty"`
+
+	// Isolation is "worktree" if claude runs in a git worktree of Directory
+	// on Branch, created from BaseCommit at WorktreePath.
+	Isolation    string `json:"isolation,omitempty"`
+	WorktreePath string `json:"worktree_path,omitempty"`
+	Branch       string `json:"branch,omitempty"`
+	BaseCommit   string `json:"base_commit,omitempty"`
+
+	// Git is the repository state around the run, if Directory is in one.
+	Git *GitInfo `json:"git,omitempty"`
+
+	// How claude exited. ExitCode is nil until the process has been reaped
+	// (or if it never started); Signal is set when it was terminated by one.
+	ExitCode      *int   `json:"exit_code,omitempty"`
+	Signal        string `json:"signal,omitempty"`
+	ErrorReason   string `json:"error_reason,omitempty"`
+	ResultSubtype string `json:"result_subtype,omitempty"`
+
+	// Metrics from claude's final result event.
+	CostUSD             float64 `json:"cost_usd,omitempty"`
+	InputTokens         int64   `json:"input_tokens,omitempty"`
+	OutputTokens        int64   `json:"output_tokens,omitempty"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens,omitempty"`
+	CacheReadTokens     int64   `json:"cache_read_tokens,omitempty"`
+	NumTurns            int     `json:"num_turns,omitempty"`
+	DurationMS          int64   `json:"duration_ms,omitempty"`
+
+	Archived bool `json:"archived,omitempty"`
+}
+
+// GitInfo records what a worker changed in its repository. Base and End are
+// the commits to diff: HEAD at start/finish, or when the tree was dirty an
+// unreferenced snapshot commit that includes the uncommitted changes.
+type GitInfo struct {
+	Branch     string       `json:"branch,omitempty"`
+	StartHead  string       `json:"start_head"`
+	StartDirty bool         `json:"start_dirty,omitempty"`
+	Base       string       `json:"base"`
+	EndHead    string       `json:"end_head,omitempty"`
+	End        string       `json:"end,omitempty"`
+	Commits    []string     `json:"commits,omitempty"` // "<abbrev> <subject>", oldest first
+	Files      []FileChange `json:"files,omitempty"`
+	Untracked  []string     `json:"untracked,omitempty"` // untracked at finish, not in the diff
+}
+
+// FileChange is one file's line counts between Base and End. Binary files
+// count -1.
+type FileChange struct {
+	Path    string `json:"path"`
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+}
+
+// PausedFor is how long w has spent paused as of now, including a pause
+// still in progress.
+func (w *Worker) PausedFor(now time.Time) time.Duration {
+	d := time.Duration(w.PausedMS) * time.Millisecond
+	if w.PausedAt != nil {
+		d += now.Sub(*w.PausedAt)
+	}
+	return d
 }
 
 func statePath(dir, id string) string {
@@ -37,6 +157,10 @@ func statePath(dir, id string) string {
 }
 
 func Write(dir string, w *Worker) error {
+	if w.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("worker %s was written by a newer ccl (schema %d, this build supports %d)", w.ID, w.SchemaVersion, SchemaVersion)
+	}
+	w.SchemaVersion = SchemaVersion
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
@@ -56,13 +180,14 @@ func Read(dir, id string) (*Worker, error) {
 	if err != nil {
 		return nil, fmt.Errorf("worker %s: %w", id, err)
 	}
-	var w Worker
-	if err := json.Unmarshal(data, &w); err != nil {
+	w, err := decode(data)
+	if err != nil {
 		return nil, fmt.Errorf("worker %s: %w", id, err)
 	}
-	return &w, nil
+	return w, nil
 }
 
+// List returns the live workers in dir, oldest first.
 func List(dir string) ([]*Worker, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
@@ -84,12 +209,15 @@ func List(dir string) ([]*Worker, error) {
 		workers = append(workers, w)
 	}
 	sort.Slice(workers, func(i, j int) bool {
-		return workers[i].ID < workers[j].ID
+		return createdBefore(workers[i], workers[j])
 	})
 	return workers, nil
 }
 
 func Delete(dir, id string) error {
 	os.Remove(filepath.Join(dir, id+".log"))
+	os.Remove(journalPath(dir, id))
+	os.Remove(taskPath(dir, id))
+	os.Remove(lockPath(dir, id))
 	return os.Remove(statePath(dir, id))
 }
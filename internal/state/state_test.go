@@ -3,7 +3,10 @@ package state
 import (
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func tempStateDir(t *testing.T) string {
@@ -82,3 +85,471 @@ func TestDelete(t *testing.T) {
 		t.Error("log file should be deleted too")
 	}
 }
+
+func TestNewIDSortable(t *testing.T) {
+	earlier := NewID(time.Unix(1700000000, 0))
+	later := NewID(time.Unix(1700000000, int64(time.Millisecond)))
+	if earlier >= later {
+		t.Errorf("expected %s < %s", earlier, later)
+	}
+	if NewID(time.Unix(1700000000, 0)) == earlier {
+		t.Error("IDs created in the same millisecond should differ")
+	}
+}
+
+func TestListCreationOrder(t *testing.T) {
+	dir := tempStateDir(t)
+	base := time.Now()
+	var want []string
+	for i := 0; i < 20; i++ {
+		// All within one millisecond, so the IDs alone can't order them.
+		created := base.Add(time.Duration(i) * time.Microsecond)
+		w := &Worker{Status: StatusPending, Directory: "/tmp", Task: "t", CreatedAt: &created}
+		if err := Create(dir, w); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		want = append(want, w.ID)
+	}
+	workers, _ := List(dir)
+	for i, w := range workers {
+		if w.ID != want[i] {
+			t.Fatalf("expected creation order %v, got %s at %d", want, w.ID, i)
+		}
+	}
+	if id, _ := Resolve(dir, LastAlias); id != want[len(want)-1] {
+		t.Errorf("last = %s, want %s", id, want[len(want)-1])
+	}
+}
+
+func TestCreateUnique(t *testing.T) {
+	dir := tempStateDir(t)
+	now := time.Now()
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		w := &Worker{Status: StatusPending, Directory: "/tmp", Task: "t", CreatedAt: &now}
+		if err := Create(dir, w); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if seen[w.ID] {
+			t.Fatalf("duplicate ID %s", w.ID)
+		}
+		seen[w.ID] = true
+	}
+	workers, _ := List(dir)
+	if len(workers) != 50 {
+		t.Errorf("expected 50 workers, got %d", len(workers))
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := tempStateDir(t)
+	t1 := time.Unix(1700000000, 0)
+	t2 := time.Unix(1700000100, 0)
+	Write(dir, &Worker{ID: "abc123", Status: StatusDone, CreatedAt: &t2})
+	Write(dir, &Worker{ID: "abd456", Status: StatusDone, CreatedAt: &t1})
+	Write(dir, &Worker{ID: "xyz789", Status: StatusDone, CreatedAt: &t1})
+
+	if id, err := Resolve(dir, "abc"); err != nil || id != "abc123" {
+		t.Errorf("prefix: got %q, %v", id, err)
+	}
+	if id, err := Resolve(dir, "xyz789"); err != nil || id != "xyz789" {
+		t.Errorf("exact: got %q, %v", id, err)
+	}
+	if id, err := Resolve(dir, LastAlias); err != nil || id != "abc123" {
+		t.Errorf("last: got %q, %v", id, err)
+	}
+	_, err := Resolve(dir, "ab")
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") || !strings.Contains(err.Error(), "abd456") {
+		t.Errorf("expected ambiguous error listing candidates, got %v", err)
+	}
+	if _, err := Resolve(dir, "nope"); err == nil {
+		t.Error("expected not found error")
+	}
+}
+
+func TestTransition(t *testing.T) {
+	dir := tempStateDir(t)
+	Write(dir, &Worker{ID: "500", Status: StatusPending, Directory: "/tmp", Task: "t"})
+
+	w, err := Transition(dir, "500", []Status{StatusPending}, func(w *Worker) error {
+		w.Status = StatusWorking
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transition: %v", err)
+	}
+	if w.Status != StatusWorking {
+		t.Errorf("expected working, got %s", w.Status)
+	}
+
+	_, err = Transition(dir, "500", []Status{StatusPending}, func(w *Worker) error {
+		t.Error("fn should not run for a disallowed source status")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "not pending") {
+		t.Errorf("expected illegal transition error, got %v", err)
+	}
+	got, _ := Read(dir, "500")
+	if got.Status != StatusWorking {
+		t.Errorf("rejected transition should not write, got %s", got.Status)
+	}
+}
+
+func TestTransitionConcurrent(t *testing.T) {
+	dir := tempStateDir(t)
+	Write(dir, &Worker{ID: "501", Status: StatusPending, Directory: "/tmp", Task: "t"})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Transition(dir, "501", []Status{StatusPending}, func(w *Worker) error {
+				w.Status = StatusWorking
+				return nil
+			})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Errorf("expected exactly one successful transition, got %d", succeeded)
+	}
+}
+
+func TestRemoveChecksStatus(t *testing.T) {
+	dir := tempStateDir(t)
+	Write(dir, &Worker{ID: "502", Status: StatusWorking, Directory: "/tmp", Task: "t"})
+	if _, err := Remove(dir, "502", []Status{StatusPending}); err == nil {
+		t.Fatal("expected error removing a working worker as pending")
+	}
+	if _, err := Remove(dir, "502", []Status{StatusWorking}); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := Read(dir, "502"); err == nil {
+		t.Error("worker should be gone")
+	}
+}
+
+func TestLockSurvivesRemoval(t *testing.T) {
+	dir := t.TempDir()
+	unlockA, err := lock(dir, "900")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acquired := make(chan func())
+	go func() {
+		unlock, _ := lock(dir, "900")
+		acquired <- unlock
+	}()
+	time.Sleep(50 * time.Millisecond)
+	// Removing the worker unlinks the lock file while it is held, and a
+	// newcomer takes a lock on a fresh one.
+	os.Remove(lockPath(dir, "900"))
+	unlockC, err := lock(dir, "900")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockA()
+	select {
+	case <-acquired:
+		t.Fatal("waiter on the removed lock file got the lock alongside the newcomer")
+	case <-time.After(200 * time.Millisecond):
+	}
+	unlockC()
+	select {
+	case unlock := <-acquired:
+		unlock()
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter never got the lock")
+	}
+}
+
+func TestJournal(t *testing.T) {
+	dir := tempStateDir(t)
+	Write(dir, &Worker{ID: "600", Status: StatusWorking, Directory: "/tmp", Task: "t"})
+	AppendEvent(dir, "600", Event{Type: EventCreated, Status: StatusWorking, Actor: "alice (cli)"})
+	AppendEvent(dir, "600", Event{Type: EventPID, PID: 42})
+
+	events, err := ReadEvents(dir, "600")
+	if err != nil {
+		t.Fatalf("ReadEvents: %v", err)
+	}
+	if len(events) != 2 || events[0].Type != EventCreated || events[1].PID != 42 {
+		t.Errorf("unexpected events: %+v", events)
+	}
+	if events[0].Time.IsZero() {
+		t.Error("event time should default to now")
+	}
+	if s := events[0].Summary(); !strings.Contains(s, "by alice (cli)") {
+		t.Errorf("summary: %q", s)
+	}
+
+	Delete(dir, "600")
+	if events, _ := ReadEvents(dir, "600"); len(events) != 0 {
+		t.Error("journal should be removed with the worker")
+	}
+}
+
+func TestArchive(t *testing.T) {
+	dir := tempStateDir(t)
+	finished := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	Write(dir, &Worker{ID: "700", Status: StatusDone, Directory: "/tmp", Task: "t", FinishedAt: &finished})
+	os.WriteFile(filepath.Join(dir, "700.log"), []byte("transcript\n"), 0644)
+	AppendEvent(dir, "700", Event{Type: EventCreated})
+
+	if _, err := Archive(dir, "700", FinishedStatuses); err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if _, err := Read(dir, "700"); err == nil {
+		t.Error("archived worker should leave the live dir")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive", "2026-03", "700.log.gz")); err != nil {
+		t.Errorf("expected compressed log in month dir: %v", err)
+	}
+
+	w, err := ReadAny(dir, "700")
+	if err != nil || !w.Archived {
+		t.Fatalf("ReadAny: %+v, %v", w, err)
+	}
+	data, err := ReadLog(dir, "700")
+	if err != nil || string(data) != "transcript\n" {
+		t.Errorf("ReadLog: %q, %v", data, err)
+	}
+	if events, _ := ReadEvents(dir, "700"); len(events) != 1 {
+		t.Errorf("expected archived journal, got %d events", len(events))
+	}
+	if id, err := ResolveAny(dir, "70"); err != nil || id != "700" {
+		t.Errorf("ResolveAny: %q, %v", id, err)
+	}
+	archived, _ := ListArchived(dir)
+	if len(archived) != 1 {
+		t.Errorf("expected 1 archived worker, got %d", len(archived))
+	}
+
+	if err := Purge(dir, "700", FinishedStatuses); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if _, err := ReadAny(dir, "700"); err == nil {
+		t.Error("purged worker should be gone")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "700.lock")); !os.IsNotExist(err) {
+		t.Errorf("purging an archived worker left a lock file: %v", err)
+	}
+}
+
+func TestCreateLongTask(t *testing.T) {
+	dir := tempStateDir(t)
+	task := "\n  Add rate limiting to the API\n" + strings.Repeat("x", TaskInlineMax)
+	w := &Worker{Status: StatusDone, Directory: "/tmp", Task: task}
+	if err := Create(dir, w); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if w.Task != "Add rate limiting to the API" || w.TaskFile != w.ID+".task.md" {
+		t.Errorf("expected a summary and task file, got %q, %q", w.Task, w.TaskFile)
+	}
+	if got, err := ReadTask(dir, w); err != nil || got != task {
+		t.Errorf("ReadTask: %d bytes, %v", len(got), err)
+	}
+
+	if _, err := Archive(dir, w.ID, FinishedStatuses); err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	archived, _ := ReadAny(dir, w.ID)
+	if got, err := ReadTask(dir, archived); err != nil || got != task {
+		t.Errorf("ReadTask after archiving: %d bytes, %v", len(got), err)
+	}
+	Purge(dir, w.ID, FinishedStatuses)
+	if files, _ := filepath.Glob(filepath.Join(dir, "archive", "*", "*")); len(files) != 0 {
+		t.Errorf("expected purge to remove everything, left %v", files)
+	}
+}
+
+func TestPurgeRequiresFinished(t *testing.T) {
+	dir := tempStateDir(t)
+	Write(dir, &Worker{ID: "702", Status: StatusWorking, Directory: "/tmp", Task: "t"})
+	if err := Purge(dir, "702", FinishedStatuses); err == nil {
+		t.Fatal("expected error purging a working worker")
+	}
+	if _, err := Read(dir, "702"); err != nil {
+		t.Errorf("refused purge removed the worker: %v", err)
+	}
+
+	Write(dir, &Worker{ID: "703", Status: StatusDone, Directory: "/tmp", Task: "t"})
+	if err := Purge(dir, "703", FinishedStatuses); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "703.lock")); !os.IsNotExist(err) {
+		t.Errorf("expected the lock file removed too, got %v", err)
+	}
+}
+
+func TestArchiveRejectsWorking(t *testing.T) {
+	dir := tempStateDir(t)
+	Write(dir, &Worker{ID: "701", Status: StatusWorking, Directory: "/tmp", Task: "t"})
+	if _, err := Archive(dir, "701", FinishedStatuses); err == nil {
+		t.Fatal("expected error archiving a working worker")
+	}
+}
+
+func TestPlanGC(t *testing.T) {
+	dir := tempStateDir(t)
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time { t := now.Add(-d); return &t }
+	Write(dir, &Worker{ID: "800", Status: StatusDone, Directory: "/a", FinishedAt: ago(10 * 24 * time.Hour)})
+	Write(dir, &Worker{ID: "801", Status: StatusDone, Directory: "/a", FinishedAt: ago(3 * time.Hour)})
+	Write(dir, &Worker{ID: "802", Status: StatusDone, Directory: "/a", FinishedAt: ago(2 * time.Hour)})
+	Write(dir, &Worker{ID: "803", Status: StatusDone, Directory: "/a", FinishedAt: ago(1 * time.Hour)})
+	Write(dir, &Worker{ID: "804", Status: StatusError, Directory: "/b", FinishedAt: ago(10 * 24 * time.Hour)})
+	Write(dir, &Worker{ID: "805", Status: StatusWorking, Directory: "/a", CreatedAt: ago(30 * 24 * time.Hour)})
+
+	removals, err := PlanGC(dir, Policy{
+		MaxAge:   map[Status]time.Duration{StatusDone: 7 * 24 * time.Hour},
+		KeepLast: 2,
+	}, now)
+	if err != nil {
+		t.Fatalf("PlanGC: %v", err)
+	}
+	got := map[string]string{}
+	for _, r := range removals {
+		got[r.Worker.ID] = r.Reason
+	}
+	if len(got) != 2 || !strings.Contains(got["800"], "max age") || !strings.Contains(got["801"], "more than 2") {
+		t.Errorf("unexpected removals: %v", got)
+	}
+	if _, err := Read(dir, "800"); err != nil {
+		t.Error("PlanGC must not delete anything")
+	}
+}
+
+func TestPlanGCLogBudget(t *testing.T) {
+	dir := tempStateDir(t)
+	now := time.Now()
+	for i, id := range []string{"810", "811", "812"} {
+		finished := now.Add(time.Duration(i) * time.Minute)
+		Write(dir, &Worker{ID: id, Status: StatusDone, Directory: "/a", FinishedAt: &finished})
+		os.WriteFile(filepath.Join(dir, id+".log"), make([]byte, 100), 0644)
+	}
+	removals, _ := PlanGC(dir, Policy{MaxLogBytes: 150}, now)
+	if len(removals) != 2 || removals[0].Worker.ID != "810" || removals[1].Worker.ID != "811" {
+		t.Errorf("expected the two oldest to be dropped, got %+v", removals)
+	}
+}
+
+func TestPlanGCKeep(t *testing.T) {
+	dir := tempStateDir(t)
+	now := time.Now()
+	Write(dir, &Worker{ID: "815", Status: StatusDone, Directory: "/a", FinishedAt: &now})
+	os.WriteFile(filepath.Join(dir, "815.log"), make([]byte, 100), 0644)
+	if removals, _ := PlanGC(dir, Policy{MaxLogBytes: 50}, now, "815"); len(removals) != 0 {
+		t.Errorf("expected a kept worker not to be dropped, got %+v", removals)
+	}
+}
+
+func TestPlanGCKeepsDependencies(t *testing.T) {
+	dir := tempStateDir(t)
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time { t := now.Add(-d); return &t }
+	// C fans in on A and B, all in one directory; B finished last.
+	Write(dir, &Worker{ID: "820", Status: StatusDone, Directory: "/a", FinishedAt: ago(2 * time.Hour)})
+	Write(dir, &Worker{ID: "821", Status: StatusDone, Directory: "/a", FinishedAt: ago(1 * time.Hour)})
+	Write(dir, &Worker{ID: "822", Status: StatusBlocked, Directory: "/a", After: []string{"820", "821"}})
+	Write(dir, &Worker{ID: "823", Status: StatusDone, Directory: "/a", FinishedAt: ago(3 * time.Hour)})
+
+	removals, err := PlanGC(dir, Policy{KeepLast: 1}, now)
+	if err != nil {
+		t.Fatalf("PlanGC: %v", err)
+	}
+	if len(removals) != 1 || removals[0].Worker.ID != "823" {
+		t.Errorf("expected only 823 removed while 822 waits on 820 and 821, got %+v", removals)
+	}
+
+	// Once the dependent has run, its dependencies are ordinary workers.
+	Transition(dir, "822", []Status{StatusBlocked}, func(w *Worker) error {
+		w.Status, w.FinishedAt = StatusDone, &now
+		return nil
+	})
+	removals, _ = PlanGC(dir, Policy{KeepLast: 1}, now)
+	if len(removals) != 3 {
+		t.Errorf("expected all but the newest removed, got %+v", removals)
+	}
+}
+
+func TestReadLegacySchema(t *testing.T) {
+	dir := tempStateDir(t)
+	// Format written by scripts/mock-data.sh and pre-versioning ccl.
+	legacy := `{
+  "id": "1740700004",
+  "status": "working",
+  "directory": "/home/user/projects/api-server",
+  "task": "Build REST endpoints",
+  "pid": 99999,
+  "session_id": "aaaa0004-0000-0000-0000-000000000004",
+  "started_at": "2025-02-28T00:00:00Z"
+}`
+	os.WriteFile(filepath.Join(dir, "1740700004.json"), []byte(legacy), 0644)
+
+	w, err := Read(dir, "1740700004")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if w.SchemaVersion != SchemaVersion {
+		t.Errorf("schema = %d, want %d", w.SchemaVersion, SchemaVersion)
+	}
+	if w.CreatedAt == nil || w.CreatedAt.Unix() != 1740700004 {
+		t.Errorf("created_at not derived from ID: %v", w.CreatedAt)
+	}
+	if w.Task != "Build REST endpoints" || w.PID != 99999 {
+		t.Errorf("fields lost in migration: %+v", w)
+	}
+
+	migrated, err := Migrate(dir)
+	if err != nil || len(migrated) != 1 {
+		t.Fatalf("Migrate = %v, %v", migrated, err)
+	}
+	if v, _ := rawVersion(filepath.Join(dir, "1740700004.json")); v != SchemaVersion {
+		t.Errorf("file still at schema %d", v)
+	}
+}
+
+func TestMigrateImageToAttachments(t *testing.T) {
+	dir := tempStateDir(t)
+	v1 := `{"schema_version": 1, "id": "901", "status": "done", "directory": "/tmp", "task": "x", "image": "/tmp/shot.png"}`
+	os.WriteFile(filepath.Join(dir, "901.json"), []byte(v1), 0644)
+
+	w, err := Read(dir, "901")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(w.Attachments) != 1 || w.Attachments[0] != "/tmp/shot.png" {
+		t.Errorf("expected the image as the only attachment, got %v", w.Attachments)
+	}
+	Migrate(dir)
+	data, _ := os.ReadFile(filepath.Join(dir, "901.json"))
+	if strings.Contains(string(data), `"image"`) {
+		t.Errorf("image should be gone after migrating: %s", data)
+	}
+}
+
+func TestWriteRejectsNewerSchema(t *testing.T) {
+	dir := tempStateDir(t)
+	future := `{"schema_version": 99, "id": "900", "status": "done", "directory": "/tmp", "task": "x"}`
+	os.WriteFile(filepath.Join(dir, "900.json"), []byte(future), 0644)
+
+	w, err := Read(dir, "900")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if err := Write(dir, w); err == nil || !strings.Contains(err.Error(), "newer ccl") {
+		t.Errorf("expected newer-schema error, got %v", err)
+	}
+	if _, err := Transition(dir, "900", nil, func(*Worker) error { return nil }); err == nil {
+		t.Error("Transition should not downgrade a newer file")
+	}
+}
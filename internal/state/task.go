@@ -0,0 +1,68 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TaskInlineMax is the longest task kept inline in a worker's state file.
+// Longer ones are stored in <id>.task.md beside it, and Task holds a
+// one-line summary for listings and hooks.
+const TaskInlineMax = 2000
+
+// taskSummaryMax bounds the summary kept in Task for a task stored in a file.
+const taskSummaryMax = 120
+
+func taskPath(dir, id string) string {
+	return filepath.Join(dir, id+".task.md")
+}
+
+// storeTask moves w's task into its own file if it is too long to keep
+// inline. w.ID must be set.
+func storeTask(dir string, w *Worker) error {
+	if len(w.Task) <= TaskInlineMax {
+		return nil
+	}
+	if err := os.WriteFile(taskPath(dir, w.ID), []byte(w.Task), 0644); err != nil {
+		return fmt.Errorf("write task: %w", err)
+	}
+	w.TaskFile = filepath.Base(taskPath(dir, w.ID))
+	w.Task = summarize(w.Task)
+	return nil
+}
+
+// summarize returns the first non-blank line of task, shortened to
+// taskSummaryMax.
+func summarize(task string) string {
+	line := strings.TrimSpace(task)
+	for l := range strings.Lines(task) {
+		if l = strings.TrimSpace(l); l != "" {
+			line = l
+			break
+		}
+	}
+	if r := []rune(line); len(r) > taskSummaryMax {
+		line = string(r[:taskSummaryMax-3]) + "..."
+	}
+	return line
+}
+
+// ReadTask returns w's full task, reading it from its task file if it has
+// one. dir is the live state dir; archived workers are found in the archive.
+func ReadTask(dir string, w *Worker) (string, error) {
+	if w.TaskFile == "" {
+		return w.Task, nil
+	}
+	if w.Archived {
+		if adir := archivedDir(dir, w.ID); adir != "" {
+			dir = adir
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, w.TaskFile))
+	if err != nil {
+		return "", fmt.Errorf("read task: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,119 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+func lockPath(dir, id string) string {
+	return filepath.Join(dir, id+".lock")
+}
+
+// lock takes an exclusive flock on the worker's lock file. The returned
+// function releases it. Removing a worker unlinks its lock file while
+// holding it, so a lock taken on a file that has since been unlinked or
+// replaced is dropped and taken again on the current one; otherwise a
+// waiter on the old file and a newcomer on a new one would both hold it.
+func lock(dir, id string) (func(), error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := lockPath(dir, id)
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+			f.Close()
+			return nil, err
+		}
+		held, herr := f.Stat()
+		cur, cerr := os.Stat(path)
+		if herr == nil && cerr == nil && os.SameFile(held, cur) {
+			return func() {
+				syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+				f.Close()
+			}, nil
+		}
+		f.Close()
+		if herr != nil {
+			return nil, herr
+		}
+	}
+}
+
+// LockScheduler takes the state-dir-wide lock that serializes decisions
+// about which queued workers may start. The returned function releases it.
+func LockScheduler(dir string) (func(), error) {
+	return lock(dir, ".scheduler")
+}
+
+// Transition re-reads worker id under its lock, checks that its current
+// status is one of from (any status if from is empty), applies fn and writes
+// the result. If fn returns an error nothing is written. The updated worker
+// is returned.
+func Transition(dir, id string, from []Status, fn func(w *Worker) error) (*Worker, error) {
+	unlock, err := lock(dir, id)
+	if err != nil {
+		return nil, fmt.Errorf("lock worker %s: %w", id, err)
+	}
+	defer unlock()
+
+	w, err := Read(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	if !statusIn(w.Status, from) {
+		return w, fmt.Errorf("worker %s is %s, not %s", id, w.Status, joinStatuses(from))
+	}
+	if err := fn(w); err != nil {
+		return w, err
+	}
+	if err := Write(dir, w); err != nil {
+		return w, err
+	}
+	return w, nil
+}
+
+func statusIn(s Status, set []Status) bool {
+	if len(set) == 0 {
+		return true
+	}
+	for _, st := range set {
+		if s == st {
+			return true
+		}
+	}
+	return false
+}
+
+func joinStatuses(set []Status) string {
+	names := make([]string, len(set))
+	for i, s := range set {
+		names[i] = string(s)
+	}
+	return strings.Join(names, " or ")
+}
+
+// Remove deletes worker id under its lock if its status is one of from (any
+// status if from is empty). The removed worker is returned.
+func Remove(dir, id string, from []Status) (*Worker, error) {
+	unlock, err := lock(dir, id)
+	if err != nil {
+		return nil, fmt.Errorf("lock worker %s: %w", id, err)
+	}
+	defer unlock()
+
+	w, err := Read(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	if !statusIn(w.Status, from) {
+		return w, fmt.Errorf("worker %s is %s, not %s", id, w.Status, joinStatuses(from))
+	}
+	return w, Delete(dir, id)
+}
@@ -0,0 +1,183 @@
+// Package stats aggregates worker outcomes, durations and cost.
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// Groupings accepted by Compute.
+var Groupings = []string{"dir", "day", "week", "status", "profile"}
+
+// Filter selects the workers included in a report. Zero values match all.
+type Filter struct {
+	Since time.Time // created at or after
+	Dir   string    // directory or any subdirectory of it
+}
+
+// Group is one row of a report.
+type Group struct {
+	Key            string         `json:"key"`
+	Count          int            `json:"count"`
+	ByStatus       map[string]int `json:"by_status"`
+	Failed         int            `json:"failed"`       // finished other than done: error, timeout, killed, denied
+	SuccessRate    float64        `json:"success_rate"` // done / finished, 0 if none finished
+	MedianDuration time.Duration  `json:"-"`
+	TotalCostUSD   float64        `json:"total_cost_usd"`
+	InputTokens    int64          `json:"input_tokens"`
+	OutputTokens   int64          `json:"output_tokens"`
+}
+
+// MarshalJSON reports the median duration in milliseconds, matching the
+// duration_ms field of worker state.
+func (g Group) MarshalJSON() ([]byte, error) {
+	type plain Group
+	return json.Marshal(struct {
+		plain
+		MedianDurationMS int64 `json:"median_duration_ms"`
+	}{plain(g), g.MedianDuration.Milliseconds()})
+}
+
+// Select returns the workers matching f.
+func Select(workers []*state.Worker, f Filter) []*state.Worker {
+	dir := ""
+	if f.Dir != "" {
+		dir = absDir(f.Dir)
+	}
+	var out []*state.Worker
+	for _, w := range workers {
+		if !f.Since.IsZero() && (w.CreatedAt == nil || w.CreatedAt.Before(f.Since)) {
+			continue
+		}
+		if dir != "" {
+			wd := absDir(w.Directory)
+			if wd != dir && !strings.HasPrefix(wd, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+				continue
+			}
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// absDir returns dir as a clean absolute path, falling back to just cleaning
+// it if the working directory can't be determined.
+func absDir(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return filepath.Clean(dir)
+}
+
+// Compute groups workers by one of Groupings and aggregates each group.
+// Groups are sorted by key.
+func Compute(workers []*state.Worker, by string) ([]Group, error) {
+	keyOf, err := keyFunc(by)
+	if err != nil {
+		return nil, err
+	}
+
+	members := map[string][]*state.Worker{}
+	for _, w := range workers {
+		k := keyOf(w)
+		members[k] = append(members[k], w)
+	}
+
+	groups := make([]Group, 0, len(members))
+	for k, ws := range members {
+		groups = append(groups, aggregate(k, ws))
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Key < groups[j].Key
+	})
+	return groups, nil
+}
+
+func keyFunc(by string) (func(*state.Worker) string, error) {
+	switch by {
+	case "dir":
+		return func(w *state.Worker) string { return w.Directory }, nil
+	case "status":
+		return func(w *state.Worker) string { return string(w.Status) }, nil
+	case "profile":
+		return func(w *state.Worker) string {
+			if w.Profile == "" {
+				return "(none)"
+			}
+			return w.Profile
+		}, nil
+	case "day":
+		return func(w *state.Worker) string {
+			if w.CreatedAt == nil {
+				return "unknown"
+			}
+			return w.CreatedAt.Local().Format("2006-01-02")
+		}, nil
+	case "week":
+		return func(w *state.Worker) string {
+			if w.CreatedAt == nil {
+				return "unknown"
+			}
+			year, week := w.CreatedAt.Local().ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown grouping %q (want %s)", by, strings.Join(Groupings, ", "))
+}
+
+func aggregate(key string, ws []*state.Worker) Group {
+	g := Group{Key: key, Count: len(ws), ByStatus: map[string]int{}}
+	var finished, done int
+	var durations []time.Duration
+	for _, w := range ws {
+		g.ByStatus[string(w.Status)]++
+		g.TotalCostUSD += w.CostUSD
+		g.InputTokens += w.InputTokens
+		g.OutputTokens += w.OutputTokens
+		if w.Status.Finished() {
+			finished++
+		}
+		if w.Status == state.StatusDone {
+			done++
+		}
+		if d, ok := Duration(w); ok {
+			durations = append(durations, d)
+		}
+	}
+	g.Failed = finished - done
+	if finished > 0 {
+		g.SuccessRate = float64(done) / float64(finished)
+	}
+	g.MedianDuration = median(durations)
+	return g
+}
+
+// Duration is how long w ran: claude's reported duration if known, else
+// the time between start and finish.
+func Duration(w *state.Worker) (time.Duration, bool) {
+	if w.DurationMS > 0 {
+		return time.Duration(w.DurationMS) * time.Millisecond, true
+	}
+	if w.StartedAt != nil && w.FinishedAt != nil {
+		return w.FinishedAt.Sub(*w.StartedAt), true
+	}
+	return 0, false
+}
+
+func median(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	mid := len(ds) / 2
+	if len(ds)%2 == 1 {
+		return ds[mid]
+	}
+	return (ds[mid-1] + ds[mid]) / 2
+}
@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func at(s string) *time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+	return &t
+}
+
+func sample() []*state.Worker {
+	return []*state.Worker{
+		{ID: "a", Status: state.StatusDone, Directory: "/src/api", CreatedAt: at("2026-10-12 09:00"), DurationMS: 60000, CostUSD: 0.5, Profile: "careful"},
+		{ID: "b", Status: state.StatusDone, Directory: "/src/api", CreatedAt: at("2026-10-12 10:00"), DurationMS: 180000, CostUSD: 0.25},
+		{ID: "c", Status: state.StatusError, Directory: "/src/api/sub", CreatedAt: at("2026-10-13 09:00"), StartedAt: at("2026-10-13 09:00"), FinishedAt: at("2026-10-13 09:02"), CostUSD: 1},
+		{ID: "d", Status: state.StatusWorking, Directory: "/src/web", CreatedAt: at("2026-10-20 09:00")},
+	}
+}
+
+func TestComputeByDir(t *testing.T) {
+	groups, err := Compute(sample(), "dir")
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	if len(groups) != 3 || groups[0].Key != "/src/api" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	api := groups[0]
+	if api.Count != 2 || api.SuccessRate != 1 || api.TotalCostUSD != 0.75 {
+		t.Errorf("unexpected aggregate: %+v", api)
+	}
+	if api.MedianDuration != 2*time.Minute {
+		t.Errorf("median = %s, want 2m", api.MedianDuration)
+	}
+	if web := groups[2]; web.SuccessRate != 0 || web.MedianDuration != 0 {
+		t.Errorf("unfinished group should have no rate or median: %+v", web)
+	}
+}
+
+func TestComputeFailed(t *testing.T) {
+	ws := []*state.Worker{
+		{ID: "a", Status: state.StatusDone, Directory: "/src"},
+		{ID: "b", Status: state.StatusError, Directory: "/src"},
+		{ID: "c", Status: state.StatusTimeout, Directory: "/src"},
+		{ID: "d", Status: state.StatusKilled, Directory: "/src"},
+		{ID: "e", Status: state.StatusDenied, Directory: "/src"},
+		{ID: "f", Status: state.StatusQueued, Directory: "/src"},
+	}
+	groups, _ := Compute(ws, "dir")
+	if g := groups[0]; g.Failed != 4 || g.SuccessRate != 0.2 {
+		t.Errorf("want 4 failed and 1/5 success, got %+v", g)
+	}
+}
+
+func TestComputeByWeekStatusAndProfile(t *testing.T) {
+	groups, _ := Compute(sample(), "week")
+	if len(groups) != 2 || groups[0].Key != "2026-W42" || groups[0].Count != 3 {
+		t.Errorf("unexpected weeks: %+v", groups)
+	}
+	if groups[0].SuccessRate < 0.66 || groups[0].SuccessRate > 0.67 {
+		t.Errorf("success rate = %f, want 2/3", groups[0].SuccessRate)
+	}
+
+	groups, _ = Compute(sample(), "status")
+	if len(groups) != 3 || groups[0].Key != "done" || groups[0].Count != 2 {
+		t.Errorf("unexpected statuses: %+v", groups)
+	}
+
+	groups, _ = Compute(sample(), "profile")
+	if len(groups) != 2 || groups[0].Key != "(none)" || groups[0].Count != 3 || groups[1].Key != "careful" || groups[1].TotalCostUSD != 0.5 {
+		t.Errorf("unexpected profiles: %+v", groups)
+	}
+
+	if _, err := Compute(sample(), "color"); err == nil {
+		t.Error("expected unknown grouping error")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	got := Select(sample(), Filter{Dir: "/src/api", Since: *at("2026-10-12 09:30")})
+	if len(got) != 2 || got[0].ID != "b" || got[1].ID != "c" {
+		t.Errorf("unexpected selection: %v", got)
+	}
+	if got := Select(sample(), Filter{Dir: "/src/ap"}); len(got) != 0 {
+		t.Errorf("dir filter should match whole path components, got %d", len(got))
+	}
+	if got := Select(sample(), Filter{Dir: "/"}); len(got) != 4 {
+		t.Errorf("root should match every worker, got %d", len(got))
+	}
+}
+
+func TestSelectRelativeDir(t *testing.T) {
+	root := t.TempDir()
+	t.Chdir(root)
+	workers := []*state.Worker{
+		{ID: "a", Directory: filepath.Join(root, "api")},
+		{ID: "b", Directory: filepath.Join(root, "web") + "/"},
+	}
+	if got := Select(workers, Filter{Dir: "api"}); len(got) != 1 || got[0].ID != "a" {
+		t.Errorf("relative dir should resolve against the working directory, got %v", got)
+	}
+	if got := Select(workers, Filter{Dir: "./web/../web"}); len(got) != 1 || got[0].ID != "b" {
+		t.Errorf("both sides should be cleaned, got %v", got)
+	}
+}
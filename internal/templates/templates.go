@@ -0,0 +1,245 @@
+// Package templates loads reusable task templates: TOML files in the
+// templates directory beside the config file, one per template, named after
+// the file.
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Template is a task with {{.name}} placeholders and the settings workers
+// created from it start with.
+type Template struct {
+	Name        string   `toml:"-" json:"name"`
+	Description string   `toml:"description" json:"description,omitempty"`
+	Task        string   `toml:"task" json:"task"`                 // text/template over the variables
+	Dir         string   `toml:"dir" json:"dir,omitempty"`         // default directory; may use variables
+	Profile     string   `toml:"profile" json:"profile,omitempty"` // [profiles.<name>] to apply
+	Labels      []string `toml:"labels" json:"labels,omitempty"`
+	Vars        []Var    `toml:"vars" json:"vars,omitempty"` // optional; placeholders not declared are added
+}
+
+// Var describes a template variable. A variable without a default must be
+// given a value unless it sets required = false; default = "" also makes it
+// optional.
+type Var struct {
+	Name        string  `toml:"name" json:"name"`
+	Description string  `toml:"description" json:"description,omitempty"`
+	Default     *string `toml:"default" json:"default,omitempty"` // nil if none
+	Required    *bool   `toml:"required" json:"required,omitempty"`
+}
+
+// IsRequired reports whether v must end up with a non-empty value.
+func (v Var) IsRequired() bool {
+	if v.Required != nil {
+		return *v.Required
+	}
+	return v.Default == nil
+}
+
+// DefaultValue returns v's default, or "" if it has none.
+func (v Var) DefaultValue() string {
+	if v.Default == nil {
+		return ""
+	}
+	return *v.Default
+}
+
+// Dir is the templates directory for the config file at configPath.
+func Dir(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "templates")
+}
+
+// List loads every template in dir, by name. A missing dir has none.
+func List(dir string) ([]*Template, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+	var out []*Template
+	for _, path := range paths {
+		t, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Load loads the template called name from dir.
+func Load(dir, name string) (*Template, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+	t, err := load(filepath.Join(dir, name+".toml"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("unknown template %q (no %s)", name, filepath.Join(dir, name+".toml"))
+	}
+	return t, err
+}
+
+func load(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &Template{Name: strings.TrimSuffix(filepath.Base(path), ".toml")}
+	if _, err := toml.Decode(string(data), t); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if strings.TrimSpace(t.Task) == "" {
+		return nil, fmt.Errorf("%s: task is empty", path)
+	}
+	for _, v := range t.Vars {
+		if v.Name == "" {
+			return nil, fmt.Errorf("%s: vars entry without a name", path)
+		}
+	}
+	if err := t.addPlaceholders(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// addPlaceholders parses Task and Dir and appends a Var for each {{.name}}
+// they use that Vars doesn't declare, in order of appearance.
+func (t *Template) addPlaceholders() error {
+	for _, text := range []string{t.Task, t.Dir} {
+		tmpl, err := template.New(t.Name).Parse(text)
+		if err != nil {
+			return err
+		}
+		for _, name := range fields(tmpl.Tree.Root, false) {
+			if !slices.ContainsFunc(t.Vars, func(v Var) bool { return v.Name == name }) {
+				t.Vars = append(t.Vars, Var{Name: name})
+			}
+		}
+	}
+	return nil
+}
+
+// fields lists the variables referenced under node: .name where dot is
+// still the top-level data, and $.name anywhere. Inside range and with
+// blocks dot is rebound, so .name there is not a variable; inner says
+// node is in such a block.
+func fields(node parse.Node, inner bool) []string {
+	var out []string
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, c := range n.Nodes {
+			out = append(out, fields(c, inner)...)
+		}
+	case *parse.ActionNode:
+		out = append(out, fields(n.Pipe, inner)...)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, c := range n.Cmds {
+			out = append(out, fields(c, inner)...)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			out = append(out, fields(arg, inner)...)
+		}
+	case *parse.FieldNode:
+		if !inner {
+			out = append(out, n.Ident[0])
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			out = append(out, n.Ident[1])
+		}
+	case *parse.IfNode:
+		out = append(out, fields(n.Pipe, inner)...)
+		out = append(out, fields(n.List, inner)...)
+		out = append(out, fields(n.ElseList, inner)...)
+	case *parse.RangeNode:
+		out = append(out, rebindFields(&n.BranchNode, inner)...)
+	case *parse.WithNode:
+		out = append(out, rebindFields(&n.BranchNode, inner)...)
+	}
+	return out
+}
+
+// rebindFields lists the variables referenced by a range or with block,
+// whose body runs with dot rebound but whose pipeline and else branch
+// don't.
+func rebindFields(n *parse.BranchNode, inner bool) []string {
+	out := fields(n.Pipe, inner)
+	out = append(out, fields(n.List, true)...)
+	return append(out, fields(n.ElseList, inner)...)
+}
+
+// Render fills in the task and directory from vars, using each variable's
+// default where vars has none. Required variables left empty are an error,
+// as are values for variables the template doesn't have.
+func (t *Template) Render(vars map[string]string) (task, dir string, err error) {
+	values := map[string]string{}
+	var missing []string
+	for _, v := range t.Vars {
+		val, ok := vars[v.Name]
+		if !ok || val == "" {
+			val = v.DefaultValue()
+		}
+		if val == "" && v.IsRequired() {
+			missing = append(missing, v.Name)
+		}
+		values[v.Name] = val
+	}
+	for name := range vars {
+		if _, ok := values[name]; !ok {
+			return "", "", fmt.Errorf("template %s has no variable %q", t.Name, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", "", fmt.Errorf("template %s needs a value for: %s", t.Name, strings.Join(missing, ", "))
+	}
+
+	if task, err = t.execute(t.Task, values); err != nil {
+		return "", "", err
+	}
+	if dir, err = t.execute(t.Dir, values); err != nil {
+		return "", "", err
+	}
+	if strings.HasPrefix(dir, "~/") {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, dir[2:])
+	}
+	return strings.TrimSpace(task), dir, nil
+}
+
+func (t *Template) execute(text string, values map[string]string) (string, error) {
+	tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("template %s: %w", t.Name, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, values); err != nil {
+		return "", fmt.Errorf("template %s: %w", t.Name, err)
+	}
+	return b.String(), nil
+}
+
+// ParseVar splits a --var argument of the form NAME=VALUE.
+func ParseVar(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid var %q (want NAME=VALUE)", s)
+	}
+	return name, value, nil
+}
@@ -0,0 +1,111 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAndRender(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "bump.toml"), []byte(`
+description = "Bump a dependency"
+task = """
+Bump {{.dep}} to {{.version}}{{if .note}} ({{.note}}){{end}}.
+Run the tests afterwards."""
+dir = "~/src/{{.repo}}"
+labels = ["deps"]
+
+[[vars]]
+name = "version"
+description = "Version to bump to"
+default = "latest"
+`), 0644)
+
+	tmpl, err := Load(dir, "bump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range tmpl.Vars {
+		names = append(names, v.Name)
+	}
+	if got := strings.Join(names, " "); got != "version dep note repo" {
+		t.Errorf("expected declared vars then placeholders in order, got %q", got)
+	}
+
+	task, taskDir, err := tmpl.Render(map[string]string{"dep": "cobra", "repo": "ccl", "note": "security fix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Bump cobra to latest (security fix).\nRun the tests afterwards."; task != want {
+		t.Errorf("expected task %q, got %q", want, task)
+	}
+	home, _ := os.UserHomeDir()
+	if want := filepath.Join(home, "src", "ccl"); taskDir != want {
+		t.Errorf("expected dir %q, got %q", want, taskDir)
+	}
+
+	if _, _, err := tmpl.Render(map[string]string{"dep": "cobra"}); err == nil || !strings.Contains(err.Error(), "note, repo") {
+		t.Errorf("expected an error naming the missing vars, got %v", err)
+	}
+	if _, _, err := tmpl.Render(map[string]string{"dep": "a", "note": "b", "repo": "c", "typo": "d"}); err == nil {
+		t.Error("expected an error for an unknown var")
+	}
+	if _, err := Load(dir, "missing"); err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+}
+
+func TestListMissingDir(t *testing.T) {
+	tmpls, err := List(filepath.Join(t.TempDir(), "templates"))
+	if err != nil || len(tmpls) != 0 {
+		t.Errorf("expected no templates and no error, got %v, %v", tmpls, err)
+	}
+}
+
+func TestOptionalVarsAndScopes(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "review.toml"), []byte(`
+task = """
+Review {{.pr}}{{if .focus}}, focusing on {{.focus}}{{end}}{{if .extra}} {{.extra}}{{end}}.
+{{with .owner}}Ask {{.}} ({{len .}}) about {{$.pr}}.{{end}}"""
+
+[[vars]]
+name = "focus"
+required = false
+
+[[vars]]
+name = "extra"
+default = ""
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "each.toml"), []byte(`task = "{{range .files}}{{.Path}} {{$.verb}}{{else}}{{.none}}{{end}}"`), 0644)
+
+	tmpls, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want, tmpl := range map[string]*Template{"files verb none": tmpls[0], "focus extra pr owner": tmpls[1]} {
+		var names []string
+		for _, v := range tmpl.Vars {
+			names = append(names, v.Name)
+		}
+		// Path is a field of the range's elements, not a variable.
+		if got := strings.Join(names, " "); got != want {
+			t.Errorf("%s: expected vars %q, got %q", tmpl.Name, want, got)
+		}
+	}
+
+	review := tmpls[1]
+	task, _, err := review.Render(map[string]string{"pr": "#12", "owner": "sam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Review #12.\nAsk sam (3) about #12."; task != want {
+		t.Errorf("expected task %q, got %q", want, task)
+	}
+	if _, _, err := review.Render(map[string]string{"pr": "#12"}); err == nil || !strings.HasSuffix(err.Error(), ": owner") {
+		t.Errorf("expected only owner missing, got %v", err)
+	}
+}
@@ -4,9 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -14,8 +12,8 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/google/uuid"
 	"github.com/scottstav/wreccless/internal/config"
-	"github.com/scottstav/wreccless/internal/hooks"
 	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/templates"
 	"github.com/scottstav/wreccless/internal/worker"
 )
 
@@ -30,10 +28,13 @@ const (
 type tickMsg time.Time
 type flashDismissMsg struct{}
 
-// ResumeInfo holds data needed to exec into claude after TUI exits.
+// ResumeInfo holds data needed to exec into the worker's agent after TUI
+// exits.
 type ResumeInfo struct {
 	SessionID string
 	Directory string
+	Command   []string // argv, program first
+	Env       []string
 }
 
 // App is the root Bubble Tea model.
@@ -95,7 +96,7 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.logView.width = msg.Width
 			a.logView.height = msg.Height
 			a.logView.viewport.Width = msg.Width
-			a.logView.viewport.Height = msg.Height - 4
+			a.logView.viewport.Height = msg.Height - 5
 		}
 		return a, nil
 
@@ -131,6 +132,24 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case actionMsg:
 		return a.handleAction(msg)
+
+	case killedMsg:
+		if msg.err != nil {
+			a.dashboard.flash = fmt.Sprintf("Error: %v", msg.err)
+			a.dashboard.flashErr = true
+		} else if cfg, err := config.Load(a.configPath); err != nil {
+			a.dashboard.flash = fmt.Sprintf("Worker %s killed; error: config: %v", msg.id, err)
+			a.dashboard.flashErr = true
+		} else {
+			worker.FireHooks(a.stateDir, "on_kill", worker.ConfigFor(cfg, msg.worker).Hooks.OnKill, worker.HookVars(msg.worker))
+			// Workers waiting on it may be settled now.
+			a.promote(cfg)
+			a.dashboard.flash = fmt.Sprintf("Worker %s killed", msg.id)
+			a.dashboard.flashErr = false
+		}
+		a.dashboard.refreshWorkers()
+		a.dashboard.refreshLogPreview()
+		return a, flashCmd()
 	}
 
 	// Route to active view
@@ -161,7 +180,18 @@ func (a App) updateDashboard(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case key.Matches(msg, dashboardKeys.New):
 			history := loadDirHistory(a.dirHistoryPath())
-			a.form = newForm(a.width, a.height, history)
+			cfg, err := config.Load(a.configPath)
+			if err != nil {
+				a.dashboard.flash = fmt.Sprintf("Error: config: %v", err)
+				a.dashboard.flashErr = true
+				return a, flashCmd()
+			}
+			tmpls, err := templates.List(templates.Dir(a.configPath))
+			if err != nil {
+				a.dashboard.flash = fmt.Sprintf("Error: templates: %v", err)
+				a.dashboard.flashErr = true
+			}
+			a.form = newForm(a.width, a.height, history, cfg.ProfileNames(), tmpls)
 			a.view = viewForm
 			return a, a.form.Init()
 
@@ -174,21 +204,32 @@ func (a App) updateDashboard(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return a, func() tea.Msg { return actionMsg{action: "deny", worker: w} }
 			}
 		case key.Matches(msg, dashboardKeys.Kill):
-			if w := a.dashboard.selectedWorker(); w != nil && w.Status == state.StatusWorking {
+			if w := a.dashboard.selectedWorker(); w != nil && w.Status.In(killable) {
 				return a, func() tea.Msg { return actionMsg{action: "kill", worker: w} }
 			}
+		case key.Matches(msg, dashboardKeys.Pause):
+			if w := a.dashboard.selectedWorker(); w != nil {
+				if action := pauseAction(w); action != "" {
+					return a, func() tea.Msg { return actionMsg{action: action, worker: w} }
+				}
+			}
 		case key.Matches(msg, dashboardKeys.Resume):
 			if w := a.dashboard.selectedWorker(); w != nil {
 				return a, func() tea.Msg { return actionMsg{action: "resume", worker: w} }
 			}
 		case key.Matches(msg, dashboardKeys.Clean):
-			if w := a.dashboard.selectedWorker(); w != nil && (w.Status == state.StatusDone || w.Status == state.StatusError) {
+			if w := a.dashboard.selectedWorker(); w != nil && w.Status.Finished() && !w.Archived {
 				return a, func() tea.Msg { return actionMsg{action: "clean", worker: w} }
 			}
 		case key.Matches(msg, dashboardKeys.CleanAll):
 			return a, func() tea.Msg { return actionMsg{action: "cleanall", worker: nil} }
+		case key.Matches(msg, dashboardKeys.Tree):
+			a.dashboard.tree = !a.dashboard.tree
+			a.dashboard.refreshWorkers()
+			a.dashboard.refreshLogPreview()
+			return a, nil
 		case key.Matches(msg, dashboardKeys.Filter):
-			filters := []string{"", "pending", "working", "done", "error"}
+			filters := []string{"", "pending", "queued", "blocked", "working", "paused", "done", "error", "timeout", "killed", "denied", "archived"}
 			cur := 0
 			for i, f := range filters {
 				if f == a.dashboard.filter {
@@ -230,31 +271,80 @@ func (a App) updateForm(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (a *App) handleCreate(msg createMsg) tea.Cmd {
-	cfg, _ := config.Load(a.configPath)
+	cfg, err := config.LoadFor(a.configPath, msg.dir)
+	if err != nil {
+		a.dashboard.flash = fmt.Sprintf("Error: config: %v", err)
+		a.dashboard.flashErr = true
+		return flashCmd()
+	}
+	if _, err := cfg.WithProfile(msg.profile); err != nil {
+		a.dashboard.flash = fmt.Sprintf("Error: %v", err)
+		a.dashboard.flashErr = true
+		return flashCmd()
+	}
+	isolation, err := worker.Isolation(cfg.Worker.Isolation, false)
+	if err != nil {
+		a.dashboard.flash = fmt.Sprintf("Error: config: %v", err)
+		a.dashboard.flashErr = true
+		return flashCmd()
+	}
+
+	timeout := cfg.Worker.DefaultTimeout.Duration
+	if msg.timeout != "" {
+		var d config.Duration
+		if err := d.Set(msg.timeout); err != nil || d.Duration < 0 {
+			a.dashboard.flash = fmt.Sprintf("Error: invalid timeout %q", msg.timeout)
+			a.dashboard.flashErr = true
+			return flashCmd()
+		}
+		timeout = d.Duration
+	}
+	attachments, err := worker.ResolveAttachments(msg.attach)
+	if err != nil {
+		a.dashboard.flash = fmt.Sprintf("Error: %v", err)
+		a.dashboard.flashErr = true
+		return flashCmd()
+	}
+	ctxFiles, err := worker.ResolveContext(msg.context)
+	if err != nil {
+		a.dashboard.flash = fmt.Sprintf("Error: %v", err)
+		a.dashboard.flashErr = true
+		return flashCmd()
+	}
 
 	now := time.Now()
-	id := strconv.FormatInt(now.Unix(), 10)
 
-	status := state.StatusWorking
+	status := state.StatusQueued
 	if msg.pending {
 		status = state.StatusPending
 	}
 
 	w := &state.Worker{
-		ID:        id,
-		Status:    status,
-		Directory: msg.dir,
-		Task:      msg.task,
-		Image:     msg.image,
-		SessionID: uuid.New().String(),
-		CreatedAt: &now,
+		Status:      status,
+		Directory:   msg.dir,
+		Task:        msg.task,
+		SessionID:   uuid.New().String(),
+		TimeoutMS:   timeout.Milliseconds(),
+		Retries:     cfg.Worker.Retries,
+		Profile:     msg.profile,
+		Template:    msg.template,
+		Labels:      msg.labels,
+		CreatedAt:   &now,
+		Attachments: attachments,
+		Context:     ctxFiles,
+		Isolation:   isolation,
+	}
+	if !msg.pending {
+		w.QueuedAt = &now
 	}
 
-	if err := state.Write(a.stateDir, w); err != nil {
+	if err := state.Create(a.stateDir, w); err != nil {
 		a.dashboard.flash = fmt.Sprintf("Error: %v", err)
 		a.dashboard.flashErr = true
 		return flashCmd()
 	}
+	id := w.ID
+	state.AppendEvent(a.stateDir, id, state.Event{Type: state.EventCreated, Status: status, Actor: state.Actor("tui")})
 
 	// Save directory to history
 	dirHistory := loadDirHistory(a.dirHistoryPath())
@@ -271,30 +361,37 @@ func (a *App) handleCreate(msg createMsg) tea.Cmd {
 	}
 	saveDirHistory(a.dirHistoryPath(), newHistory)
 
-	vars := hooks.Vars{ID: id, Task: msg.task, Dir: msg.dir, Status: string(status)}
 	if msg.pending {
-		hooks.Fire(cfg.Hooks.OnPending, vars)
+		worker.FireHooks(a.stateDir, "on_pending", worker.ConfigFor(cfg, w).Hooks.OnPending, worker.HookVars(w))
 		a.dashboard.flash = fmt.Sprintf("Worker %s created (pending)", id)
 	} else {
-		startedAt := time.Now()
-		w.StartedAt = &startedAt
-		state.Write(a.stateDir, w)
-
-		cclBin, _ := os.Executable()
-		if err := worker.SpawnRun(id, cclBin, a.configPath, a.stateDir); err != nil {
+		w, err := worker.Schedule(a.stateDir, a.configPath, cfg, id)
+		if err != nil {
 			a.dashboard.flash = fmt.Sprintf("Error spawning: %v", err)
 			a.dashboard.flashErr = true
 			return flashCmd()
 		}
-		hooks.Fire(cfg.Hooks.OnStart, vars)
-		a.dashboard.flash = fmt.Sprintf("Worker %s created", id)
+		if w.Status == state.StatusQueued {
+			a.dashboard.flash = fmt.Sprintf("Worker %s created (queued)", id)
+		} else {
+			a.dashboard.flash = fmt.Sprintf("Worker %s created", id)
+		}
+	}
+	if ignored := cfg.Ignored(); len(ignored) > 0 {
+		a.dashboard.flash += fmt.Sprintf("; ignored %s", strings.Join(ignored, "; "))
 	}
 	a.dashboard.flashErr = false
 	return flashCmd()
 }
 
+// promote starts whatever queued workers the concurrency limits allow,
+// after settling blocked ones whose dependencies a stopped worker decided.
+func (a *App) promote(cfg *config.Config) {
+	cclBin, _ := os.Executable()
+	worker.Promote(a.stateDir, cfg, worker.SpawnLauncher(cclBin, a.configPath, a.stateDir))
+}
+
 func (a App) handleAction(msg actionMsg) (tea.Model, tea.Cmd) {
-	cfg, _ := config.Load(a.configPath)
 	w := msg.worker
 	// Re-read from disk to avoid stale state
 	if w != nil {
@@ -302,54 +399,104 @@ func (a App) handleAction(msg actionMsg) (tea.Model, tea.Cmd) {
 			w = fresh
 		}
 	}
+	var cfg *config.Config
+	switch msg.action {
+	case "approve", "deny", "kill", "resume":
+		var err error
+		if cfg, err = config.LoadFor(a.configPath, w.Directory); err != nil {
+			a.dashboard.flash = fmt.Sprintf("Error: config: %v", err)
+			a.dashboard.flashErr = true
+			return a, flashCmd()
+		}
+	}
 
 	switch msg.action {
 	case "approve":
-		now := time.Now()
-		w.Status = state.StatusWorking
-		w.StartedAt = &now
-		if w.SessionID == "" {
-			w.SessionID = uuid.New().String()
+		w, err := state.Transition(a.stateDir, w.ID, []state.Status{state.StatusPending}, func(w *state.Worker) error {
+			if len(w.After) > 0 {
+				w.Status = state.StatusBlocked
+				return nil
+			}
+			now := time.Now()
+			w.Status = state.StatusQueued
+			w.QueuedAt = &now
+			return nil
+		})
+		if err != nil {
+			a.dashboard.flash = fmt.Sprintf("Error: %v", err)
+			a.dashboard.flashErr = true
+			break
 		}
-		state.Write(a.stateDir, w)
+		state.AppendEvent(a.stateDir, w.ID, state.Event{Type: state.EventApproved, Actor: state.Actor("tui")})
 
-		cclBin, _ := os.Executable()
-		if err := worker.SpawnRun(w.ID, cclBin, a.configPath, a.stateDir); err != nil {
+		if w, err := worker.Schedule(a.stateDir, a.configPath, cfg, w.ID); err != nil {
 			a.dashboard.flash = fmt.Sprintf("Error: %v", err)
 			a.dashboard.flashErr = true
+		} else if w.Status == state.StatusQueued || w.Status == state.StatusBlocked {
+			a.dashboard.flash = fmt.Sprintf("Worker %s approved (%s)", w.ID, w.Status)
+			a.dashboard.flashErr = false
 		} else {
-			vars := hooks.Vars{ID: w.ID, Task: w.Task, Dir: w.Directory, Status: "working"}
-			hooks.Fire(cfg.Hooks.OnStart, vars)
 			a.dashboard.flash = fmt.Sprintf("Worker %s approved", w.ID)
 			a.dashboard.flashErr = false
 		}
 
 	case "deny":
-		state.Delete(a.stateDir, w.ID)
-		vars := hooks.Vars{ID: w.ID, Task: w.Task, Dir: w.Directory, Status: "denied"}
-		hooks.Fire(cfg.Hooks.OnKill, vars)
+		actor := state.Actor("tui")
+		w, err := state.Transition(a.stateDir, w.ID, []state.Status{state.StatusPending}, func(w *state.Worker) error {
+			now := time.Now()
+			w.Status = state.StatusDenied
+			w.FinishedAt = &now
+			w.StoppedBy = actor
+			return nil
+		})
+		if err != nil {
+			a.dashboard.flash = fmt.Sprintf("Error: %v", err)
+			a.dashboard.flashErr = true
+			break
+		}
+		state.AppendEvent(a.stateDir, w.ID, state.Event{Type: state.EventDenied, Actor: actor})
+		vars := worker.HookVars(w)
+		worker.FireHooks(a.stateDir, "on_kill", worker.ConfigFor(cfg, w).Hooks.OnKill, vars)
+		a.promote(cfg)
 		a.dashboard.flash = fmt.Sprintf("Worker %s denied", w.ID)
 		a.dashboard.flashErr = false
-		if a.view == viewLogView {
-			a.view = viewDashboard
-		}
 
 	case "kill":
-		if w.PID > 0 {
-			syscall.Kill(w.PID, syscall.SIGTERM)
+		// Stopping waits out the kill grace period, so don't block the UI.
+		id, grace := w.ID, cfg.Worker.KillGrace.Duration
+		a.dashboard.flash = fmt.Sprintf("Stopping worker %s…", id)
+		a.dashboard.flashErr = false
+		a.dashboard.refreshWorkers()
+		return a, func() tea.Msg {
+			w, err := worker.Kill(a.stateDir, id, state.Actor("tui"), grace, false)
+			return killedMsg{id: id, worker: w, err: err}
+		}
+
+	case "pause":
+		if _, err := worker.Pause(a.stateDir, w.ID, state.Actor("tui")); err != nil {
+			a.dashboard.flash = fmt.Sprintf("Error: %v", err)
+			a.dashboard.flashErr = true
+			break
 		}
-		state.Delete(a.stateDir, w.ID)
-		vars := hooks.Vars{ID: w.ID, Task: w.Task, Dir: w.Directory, Status: "killed"}
-		hooks.Fire(cfg.Hooks.OnKill, vars)
-		a.dashboard.flash = fmt.Sprintf("Worker %s killed", w.ID)
+		a.dashboard.flash = fmt.Sprintf("Worker %s paused", w.ID)
 		a.dashboard.flashErr = false
-		if a.view == viewLogView {
-			a.view = viewDashboard
+
+	case "unpause":
+		if _, err := worker.Unpause(a.stateDir, w.ID, state.Actor("tui")); err != nil {
+			a.dashboard.flash = fmt.Sprintf("Error: %v", err)
+			a.dashboard.flashErr = true
+			break
 		}
+		a.dashboard.flash = fmt.Sprintf("Worker %s unpaused", w.ID)
+		a.dashboard.flashErr = false
 
 	case "clean":
-		state.Delete(a.stateDir, w.ID)
-		a.dashboard.flash = fmt.Sprintf("Worker %s cleaned", w.ID)
+		if _, err := worker.Archive(a.stateDir, w.ID, state.FinishedStatuses); err != nil {
+			a.dashboard.flash = fmt.Sprintf("Error: %v", err)
+			a.dashboard.flashErr = true
+			break
+		}
+		a.dashboard.flash = fmt.Sprintf("Worker %s archived", w.ID)
 		a.dashboard.flashErr = false
 		if a.view == viewLogView {
 			a.view = viewDashboard
@@ -359,24 +506,30 @@ func (a App) handleAction(msg actionMsg) (tea.Model, tea.Cmd) {
 		workers, _ := state.List(a.stateDir)
 		count := 0
 		for _, w := range workers {
-			if w.Status == state.StatusDone || w.Status == state.StatusError {
-				state.Delete(a.stateDir, w.ID)
+			if _, err := worker.Archive(a.stateDir, w.ID, state.FinishedStatuses); err == nil {
 				count++
 			}
 		}
-		a.dashboard.flash = fmt.Sprintf("Cleaned %d worker(s)", count)
+		a.dashboard.flash = fmt.Sprintf("Archived %d worker(s)", count)
 		a.dashboard.flashErr = false
 
 	case "resume":
 		if w.SessionID == "" {
 			a.dashboard.flash = "No session to resume"
 			a.dashboard.flashErr = true
+		} else if argv, env, err := a.resumeCommand(cfg, w); err != nil {
+			a.dashboard.flash = fmt.Sprintf("Error: %v", err)
+			a.dashboard.flashErr = true
 		} else {
 			a.ResumeWorker = &ResumeInfo{
 				SessionID: w.SessionID,
-				Directory: w.Directory,
+				Directory: worker.WorkDir(w),
+				Command:   argv,
+				Env:       env,
+			}
+			if !w.Archived {
+				state.AppendEvent(a.stateDir, w.ID, state.Event{Type: state.EventResumed, Actor: state.Actor("tui")})
 			}
-			state.Delete(a.stateDir, w.ID)
 			return a, tea.Quit
 		}
 	}
@@ -386,6 +539,26 @@ func (a App) handleAction(msg actionMsg) (tea.Model, tea.Cmd) {
 	return a, flashCmd()
 }
 
+// resumeCommand is the command and environment that open w's session
+// interactively.
+func (a *App) resumeCommand(cfg *config.Config, w *state.Worker) (argv, env []string, err error) {
+	if w.Task, err = state.ReadTask(a.stateDir, w); err != nil {
+		return nil, nil, err
+	}
+	cfg = worker.ConfigFor(cfg, w)
+	agent, err := worker.RunnerFor(cfg, w.Runner)
+	if err != nil {
+		return nil, nil, err
+	}
+	if argv, err = agent.InteractiveCommand(w); err != nil {
+		return nil, nil, err
+	}
+	if env, err = worker.Environ(a.stateDir, w, cfg); err != nil {
+		return nil, nil, err
+	}
+	return argv, env, nil
+}
+
 func (a App) View() string {
 	if a.width == 0 {
 		return "Loading..."
@@ -435,12 +608,14 @@ func (a App) renderHelp() string {
 		{"Enter", "Open log viewer"},
 		{"n", "New worker"},
 		{"/", "Cycle status filter"},
+		{"t", "Toggle dependency tree"},
 		{"a", "Approve pending worker"},
 		{"d", "Deny pending worker"},
-		{"x", "Kill working worker"},
+		{"x", "Kill working, queued or blocked worker"},
+		{"p", "Pause / unpause working worker"},
 		{"r", "Resume worker session"},
-		{"c", "Clean done/error worker"},
-		{"C", "Clean all done/error"},
+		{"c", "Archive finished worker"},
+		{"C", "Archive all finished"},
 	})
 
 	section("Log Viewer", [][2]string{
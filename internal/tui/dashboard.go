@@ -3,9 +3,7 @@ package tui
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
-	"syscall"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -13,6 +11,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/scottstav/wreccless/internal/logrender"
 	"github.com/scottstav/wreccless/internal/state"
+	"github.com/scottstav/wreccless/internal/worker"
 )
 
 type dashboard struct {
@@ -27,6 +26,8 @@ type dashboard struct {
 	filter     string
 	flash      string
 	flashErr   bool
+	tree       bool     // order workers by their --after dependencies
+	branches   []string // per worker in tree mode, the tree lines before its task
 }
 
 func newDashboard(stateDir, configPath string) dashboard {
@@ -73,17 +74,20 @@ func (d dashboard) handleKey(msg tea.KeyMsg) (dashboard, tea.Cmd) {
 }
 
 func (d *dashboard) refreshWorkers() {
+	if d.filter == "archived" {
+		workers, err := state.ListArchived(d.stateDir)
+		if err != nil {
+			return
+		}
+		d.setWorkers(workers)
+		return
+	}
+
 	workers, err := state.List(d.stateDir)
 	if err != nil {
 		return
 	}
-	// Stale detection
-	for _, w := range workers {
-		if w.Status == state.StatusWorking && w.PID > 0 && !isAlive(w.PID) {
-			w.Status = state.StatusError
-			state.Write(d.stateDir, w)
-		}
-	}
+	worker.MarkStale(d.stateDir, workers)
 	// Apply filter
 	if d.filter != "" {
 		var filtered []*state.Worker
@@ -94,6 +98,14 @@ func (d *dashboard) refreshWorkers() {
 		}
 		workers = filtered
 	}
+	d.setWorkers(workers)
+}
+
+func (d *dashboard) setWorkers(workers []*state.Worker) {
+	d.branches = nil
+	if d.tree {
+		workers, d.branches = dependencyTree(workers)
+	}
 	d.workers = workers
 	if d.cursor >= len(d.workers) && len(d.workers) > 0 {
 		d.cursor = len(d.workers) - 1
@@ -103,11 +115,56 @@ func (d *dashboard) refreshWorkers() {
 	}
 }
 
-func isAlive(pid int) bool {
-	if pid <= 0 {
-		return false
+// dependencyTree orders workers depth first from those that wait on no
+// other listed worker, each followed by the workers waiting on it, and
+// returns the tree lines to draw before each one. A worker waiting on
+// several is placed under the first of them that is listed.
+func dependencyTree(workers []*state.Worker) ([]*state.Worker, []string) {
+	listed := map[string]bool{}
+	for _, w := range workers {
+		listed[w.ID] = true
+	}
+	children := map[string][]*state.Worker{}
+	var roots []*state.Worker
+	for _, w := range workers {
+		parent := ""
+		for _, dep := range w.After {
+			if listed[dep] {
+				parent = dep
+				break
+			}
+		}
+		if parent == "" {
+			roots = append(roots, w)
+		} else {
+			children[parent] = append(children[parent], w)
+		}
+	}
+
+	var ordered []*state.Worker
+	var branches []string
+	var walk func(w *state.Worker, indent, branch string)
+	walk = func(w *state.Worker, indent, branch string) {
+		ordered = append(ordered, w)
+		branches = append(branches, indent+branch)
+		if branch == "├─ " {
+			indent += "│  "
+		} else if branch == "└─ " {
+			indent += "   "
+		}
+		kids := children[w.ID]
+		for i, c := range kids {
+			if i == len(kids)-1 {
+				walk(c, indent, "└─ ")
+			} else {
+				walk(c, indent, "├─ ")
+			}
+		}
+	}
+	for _, r := range roots {
+		walk(r, "", "")
 	}
-	return syscall.Kill(pid, 0) == nil
+	return ordered, branches
 }
 
 func (d *dashboard) refreshLogPreview() {
@@ -116,18 +173,18 @@ func (d *dashboard) refreshLogPreview() {
 		d.logContent = ""
 		return
 	}
-	logPath := filepath.Join(d.stateDir, w.ID+".log")
-	data, err := os.ReadFile(logPath)
+	data, err := state.ReadLog(d.stateDir, w.ID)
 	if err != nil {
 		d.logContent = mutedStyle.Render("No logs yet.")
 		return
 	}
+	parse := logrender.Parser(w.LogFormat)
 	var lines []string
 	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
 		if line == "" {
 			continue
 		}
-		events := logrender.ParseLine([]byte(line))
+		events := parse([]byte(line))
 		for _, e := range events {
 			switch e.Type {
 			case logrender.EventText:
@@ -237,8 +294,14 @@ func (d dashboard) renderTable(maxRows int) string {
 	var b strings.Builder
 	home, _ := os.UserHomeDir()
 
+	// IDs from state.NewID are 12 characters, older ones may differ.
+	idWidth := len("ID")
+	for _, w := range d.workers {
+		idWidth = max(idWidth, len(w.ID))
+	}
+
 	// Header
-	header := fmt.Sprintf("  %-8s %-10s %-24s %s", "ID", "STATUS", "DIRECTORY", "TASK")
+	header := fmt.Sprintf("  %-*s %-10s %7s %-24s %s", idWidth, "ID", "STATUS", "COST", "DIRECTORY", "TASK")
 	b.WriteString(headerStyle.Render(header))
 	b.WriteString("\n")
 
@@ -269,20 +332,28 @@ func (d dashboard) renderTable(maxRows int) string {
 		}
 
 		task := w.Task
-		maxTask := d.width - 50
+		maxTask := d.width - 48 - idWidth
 		if maxTask < 10 {
 			maxTask = 10
 		}
 		if len(task) > maxTask {
 			task = task[:maxTask-3] + "..."
 		}
+		if i < len(d.branches) {
+			task = d.branches[i] + task
+		}
 
 		status := d.renderStatus(w)
-		id := w.ID
+		// Pad before styling, which adds escape codes to the width.
+		id := fmt.Sprintf("%-*s", idWidth, w.ID)
 		if i == d.cursor {
 			id = selectedStyle.Render(id)
 		}
-		row := fmt.Sprintf("  %-8s %-10s %-24s %s", id, status, dir, task)
+		cost := ""
+		if w.NumTurns > 0 || w.CostUSD > 0 {
+			cost = fmt.Sprintf("$%.2f", w.CostUSD)
+		}
+		row := fmt.Sprintf("  %s %-10s %7s %-24s %s", id, status, cost, dir, task)
 		b.WriteString(normalRowStyle.Render(row))
 		b.WriteString("\n")
 	}
@@ -292,39 +363,87 @@ func (d dashboard) renderTable(maxRows int) string {
 func (d dashboard) renderStatus(w *state.Worker) string {
 	switch w.Status {
 	case state.StatusWorking:
+		if w.StopRequestedAt != nil {
+			return statusStopped.Render(d.spinner.View() + " stopping")
+		}
 		return statusWorking.Render(d.spinner.View() + " working")
 	case state.StatusPending:
 		return statusPending.Render("◔ pending")
+	case state.StatusQueued:
+		return statusPending.Render("◷ queued")
+	case state.StatusBlocked:
+		return statusPending.Render("⧗ blocked")
+	case state.StatusPaused:
+		return statusPending.Render("⏸ paused")
 	case state.StatusDone:
 		return statusDone.Render("✓ done")
 	case state.StatusError:
+		if why := exitSummary(w); why != "" {
+			return statusError.Render("✗ error " + why)
+		}
 		return statusError.Render("✗ error")
+	case state.StatusTimeout:
+		return statusError.Render("⏱ timeout")
+	case state.StatusKilled:
+		return statusStopped.Render("■ killed")
+	case state.StatusDenied:
+		return statusStopped.Render("⊘ denied")
 	}
 	return string(w.Status)
 }
 
+// exitSummary is a short hint at why a worker failed: the terminating
+// signal, claude's error result subtype, or a non-zero exit code.
+func exitSummary(w *state.Worker) string {
+	switch {
+	case w.Signal != "":
+		return w.Signal
+	case strings.HasPrefix(w.ResultSubtype, "error_"):
+		return strings.TrimPrefix(w.ResultSubtype, "error_")
+	case w.ExitCode != nil && *w.ExitCode != 0:
+		return fmt.Sprintf("exit %d", *w.ExitCode)
+	}
+	return ""
+}
+
 func (d dashboard) renderHelp() string {
 	var parts []string
 	add := func(k, desc string) {
 		parts = append(parts, helpKeyStyle.Render(k)+" "+helpDescStyle.Render(desc))
 	}
 
-	if w := d.selectedWorker(); w != nil {
+	if w := d.selectedWorker(); w != nil && w.Archived {
+		add("[r]", "resume")
+		add("[enter]", "logs")
+	} else if w != nil {
 		switch w.Status {
 		case state.StatusPending:
 			add("[a]", "approve")
 			add("[d]", "deny")
 		case state.StatusWorking:
 			add("[x]", "kill")
+			add("[p]", "pause")
 			add("[r]", "resume")
-		case state.StatusDone, state.StatusError:
+		case state.StatusPaused:
+			add("[x]", "kill")
+			add("[p]", "unpause")
+		case state.StatusQueued, state.StatusBlocked:
+			add("[x]", "kill")
+		case state.StatusDone, state.StatusError, state.StatusKilled, state.StatusTimeout:
 			add("[r]", "resume")
 			add("[c]", "clean")
+		case state.StatusDenied:
+			add("[c]", "clean")
 		}
 		add("[enter]", "logs")
 	}
 
 	add("[n]", "new")
+	if d.tree {
+		add("[t]", "list")
+	} else {
+		add("[t]", "tree")
+	}
 	if d.filter != "" {
 		add("[/]", "filter:"+d.filter)
 	} else {
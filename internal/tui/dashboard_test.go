@@ -3,6 +3,7 @@ package tui
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -124,3 +125,78 @@ func TestDashboardFilter(t *testing.T) {
 		t.Errorf("expected worker 101, got %s", d.workers[0].ID)
 	}
 }
+
+func TestDashboardArchivedFilter(t *testing.T) {
+	dir := setupTestWorkers(t)
+	if _, err := state.Archive(dir, "102", state.FinishedStatuses); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	d := newDashboard(dir, "")
+	d.refreshWorkers()
+	if len(d.workers) != 2 {
+		t.Fatalf("expected archived worker hidden by default, got %d", len(d.workers))
+	}
+	d.filter = "archived"
+	d.refreshWorkers()
+	if len(d.workers) != 1 || !d.workers[0].Archived {
+		t.Fatalf("expected 1 archived worker, got %+v", d.workers)
+	}
+}
+
+func TestDashboardIDColumn(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	state.Write(dir, &state.Worker{ID: state.NewID(now), Status: state.StatusDone, Directory: "/tmp/a", Task: "first", CreatedAt: &now})
+	later := now.Add(time.Second)
+	state.Write(dir, &state.Worker{ID: state.NewID(later), Status: state.StatusPending, Directory: "/tmp/b", Task: "second", CreatedAt: &later})
+	d := newDashboard(dir, "")
+	d.width = 120
+	d.refreshWorkers()
+
+	lines := strings.Split(d.renderTable(0), "\n")
+	want := strings.Index(lines[0], "STATUS")
+	for i, w := range d.workers {
+		// The status follows the ID and a single space.
+		if got := strings.Index(lines[i+1], w.ID) + len(w.ID) + 1; got != want {
+			t.Errorf("status at column %d, header at %d:\n%s\n%s", got, want, lines[0], lines[i+1])
+		}
+	}
+}
+
+func TestExitSummary(t *testing.T) {
+	code := 2
+	cases := []struct {
+		w    state.Worker
+		want string
+	}{
+		{state.Worker{Signal: "SIGKILL"}, "SIGKILL"},
+		{state.Worker{ExitCode: &code, ResultSubtype: "error_max_turns"}, "max_turns"},
+		{state.Worker{ExitCode: &code}, "exit 2"},
+		{state.Worker{}, ""},
+	}
+	for _, c := range cases {
+		if got := exitSummary(&c.w); got != c.want {
+			t.Errorf("exitSummary(%+v) = %q, want %q", c.w, got, c.want)
+		}
+	}
+}
+
+func TestDependencyTree(t *testing.T) {
+	workers := []*state.Worker{
+		{ID: "a"},
+		{ID: "b"},
+		{ID: "c", After: []string{"a"}},
+		{ID: "d", After: []string{"a", "b"}},
+		{ID: "e", After: []string{"c"}},
+		{ID: "f", After: []string{"gone"}},
+	}
+	ordered, branches := dependencyTree(workers)
+	var got []string
+	for i, w := range ordered {
+		got = append(got, branches[i]+w.ID)
+	}
+	want := []string{"a", "├─ c", "│  └─ e", "└─ d", "b", "f"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected tree:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -1,51 +1,104 @@
 package tui
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/scottstav/wreccless/internal/editor"
+	"github.com/scottstav/wreccless/internal/templates"
 )
 
 type cancelMsg struct{}
 
+// editedMsg carries the task back from $EDITOR.
+type editedMsg struct {
+	task string
+	err  error
+}
+
 type createMsg struct {
 	dir     string
 	task    string
-	image   string
+	attach  []string
+	context []string // files or globs
+	timeout string   // empty for [worker] default_timeout
+	profile string
 	pending bool
+
+	template string // applied with ctrl+t, if any
+	labels   []string
 }
 
+// Form fields in focus order. Attach, context and timeout are the text
+// inputs, in that order.
+const (
+	fieldDir = iota
+	fieldTask
+	fieldAttach
+	fieldContext
+	fieldTimeout
+	fieldProfile
+	fieldPending
+)
+
 type form struct {
 	dirPicker   dirPicker
-	inputs      []textinput.Model // task, image (2 inputs)
-	focusIndex  int               // 0=dirpicker, 1=task, 2=image, 3=pending
+	task        textarea.Model
+	inputs      []textinput.Model // attach, context, timeout
+	focusIndex  int               // one of the field constants
+	profiles    []string          // "" (no profile) then [profiles.<name>]
+	profile     int               // index into profiles
 	pending     bool
-	completions []string // for image field only
+	completions []string // paths for the last entry of attach or context
+	editErr     error    // from the last $EDITOR session
 	width       int
 	height      int
+
+	templates []*templates.Template
+	picker    *templatePicker // open while choosing a template
+	template  string          // name of the last template applied
+	labels    []string        // from that template
 }
 
-func newForm(width, height int, history []string) form {
+func newForm(width, height int, history, profiles []string, tmpls []*templates.Template) form {
 	dp := newDirPicker(history)
 	dp.Focus()
 
-	taskInput := textinput.New()
-	taskInput.Placeholder = "Describe the task..."
-	taskInput.CharLimit = 500
-	taskInput.Width = 50
+	taskInput := textarea.New()
+	taskInput.Placeholder = "Describe the task... (ctrl+e: open in $EDITOR)"
+	taskInput.CharLimit = 0
+	taskInput.ShowLineNumbers = false
+	taskInput.SetWidth(50)
+	taskInput.SetHeight(5)
 
-	imageInput := textinput.New()
-	imageInput.Placeholder = "(optional) path to image"
-	imageInput.CharLimit = 256
-	imageInput.Width = 50
+	attachInput := textinput.New()
+	attachInput.Placeholder = "(optional) images, PDFs, files; comma-separated"
+	attachInput.CharLimit = 1024
+	attachInput.Width = 50
+
+	contextInput := textinput.New()
+	contextInput.Placeholder = "(optional) files or globs to include; comma-separated"
+	contextInput.CharLimit = 1024
+	contextInput.Width = 50
+
+	timeoutInput := textinput.New()
+	timeoutInput.Placeholder = "(optional) e.g. 30m or 2d"
+	timeoutInput.CharLimit = 32
+	timeoutInput.Width = 50
 
 	return form{
 		dirPicker: dp,
-		inputs:    []textinput.Model{taskInput, imageInput},
+		task:      taskInput,
+		inputs:    []textinput.Model{attachInput, contextInput, timeoutInput},
+		profiles:  append([]string{""}, profiles...),
+		templates: tmpls,
 		width:     width,
 		height:    height,
 	}
@@ -56,6 +109,19 @@ func (f form) Init() tea.Cmd {
 }
 
 func (f form) Update(msg tea.Msg) (form, tea.Cmd) {
+	switch msg := msg.(type) {
+	case templateAppliedMsg:
+		return f.applyTemplate(msg), nil
+	case templatePickerCancelMsg:
+		f.picker = nil
+		return f, nil
+	}
+	if f.picker != nil {
+		p, cmd := f.picker.Update(msg)
+		f.picker = &p
+		return f, cmd
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		return f.handleKey(msg)
@@ -63,21 +129,72 @@ func (f form) Update(msg tea.Msg) (form, tea.Cmd) {
 		return f.advanceFocus(), nil
 	case dirPickerCancelMsg:
 		return f, func() tea.Msg { return cancelMsg{} }
+	case editedMsg:
+		f.editErr = msg.err
+		if msg.err == nil {
+			f.task.SetValue(msg.task)
+		}
+		return f, nil
 	}
+	return f.updateFocused(msg)
+}
 
-	// Update the focused component
-	if f.focusIndex == 0 {
-		var cmd tea.Cmd
+// updateFocused passes msg to the focused component.
+func (f form) updateFocused(msg tea.Msg) (form, tea.Cmd) {
+	var cmd tea.Cmd
+	switch f.focusIndex {
+	case fieldDir:
 		f.dirPicker, cmd = f.dirPicker.Update(msg)
-		return f, cmd
-	}
-	idx := f.focusIndex - 1
-	if idx >= 0 && idx < len(f.inputs) {
-		var cmd tea.Cmd
+	case fieldTask:
+		f.task, cmd = f.task.Update(msg)
+	case fieldAttach, fieldContext, fieldTimeout:
+		idx := f.focusIndex - fieldAttach
 		f.inputs[idx], cmd = f.inputs[idx].Update(msg)
-		return f, cmd
+		if _, ok := msg.(tea.KeyMsg); ok && f.focusIndex != fieldTimeout {
+			f.updatePathCompletions()
+		}
+	}
+	return f, cmd
+}
+
+// applyTemplate fills the form in from a rendered template and closes the
+// picker.
+func (f form) applyTemplate(msg templateAppliedMsg) form {
+	f.picker = nil
+	f.template = msg.name
+	f.labels = msg.labels
+	f.task.SetValue(msg.task)
+	if msg.dir != "" {
+		f.dirPicker.input.SetValue(msg.dir)
+		f.dirPicker.input.SetCursor(len(msg.dir))
+	}
+	if msg.profile != "" {
+		if i := slices.Index(f.profiles, msg.profile); i >= 0 {
+			f.profile = i
+		} else {
+			f.editErr = fmt.Errorf("template %s: unknown profile %q", msg.name, msg.profile)
+		}
+	}
+	f.setFocus(false)
+	f.focusIndex = fieldTask
+	f.setFocus(true)
+	return f
+}
+
+// editTask opens the task in $EDITOR, suspending the TUI until it exits.
+func (f form) editTask() tea.Cmd {
+	path, err := editor.WriteTemp(f.task.Value(), "Describe the task above the line. Everything from it down is ignored.")
+	if err != nil {
+		return func() tea.Msg { return editedMsg{err: err} }
 	}
-	return f, nil
+	return tea.ExecProcess(editor.Command(path), func(err error) tea.Msg {
+		defer os.Remove(path)
+		if err != nil {
+			return editedMsg{err: err}
+		}
+		task, err := editor.Read(path)
+		return editedMsg{task: task, err: err}
+	})
 }
 
 func (f form) handleKey(msg tea.KeyMsg) (form, tea.Cmd) {
@@ -92,7 +209,7 @@ func (f form) handleKey(msg tea.KeyMsg) (form, tea.Cmd) {
 
 	case key.Matches(msg, formKeys.Submit):
 		dir := f.dirPicker.Value()
-		task := f.inputs[0].Value()
+		task := strings.TrimSpace(f.task.Value())
 		if dir == "" || task == "" {
 			return f, nil
 		}
@@ -102,13 +219,26 @@ func (f form) handleKey(msg tea.KeyMsg) (form, tea.Cmd) {
 		}
 		return f, func() tea.Msg {
 			return createMsg{
-				dir:     dir,
-				task:    task,
-				image:   f.inputs[1].Value(),
-				pending: f.pending,
+				dir:      dir,
+				task:     task,
+				attach:   splitList(f.inputs[0].Value()),
+				context:  splitList(f.inputs[1].Value()),
+				timeout:  strings.TrimSpace(f.inputs[2].Value()),
+				profile:  f.profiles[f.profile],
+				pending:  f.pending,
+				template: f.template,
+				labels:   f.labels,
 			}
 		}
 
+	case key.Matches(msg, formKeys.Editor):
+		return f, f.editTask()
+
+	case key.Matches(msg, formKeys.Template) && len(f.templates) > 0:
+		p := newTemplatePicker(f.templates)
+		f.picker = &p
+		return f, nil
+
 	case key.Matches(msg, formKeys.NextField):
 		if f.focusIndex == 0 && f.dirPicker.open && f.dirPicker.cursor >= 0 {
 			var cmd tea.Cmd
@@ -121,91 +251,100 @@ func (f form) handleKey(msg tea.KeyMsg) (form, tea.Cmd) {
 		return f.retreatFocus(), nil
 
 	case key.Matches(msg, formKeys.Toggle):
-		if f.focusIndex == 3 {
+		switch f.focusIndex {
+		case fieldProfile:
+			return f.cycleProfile(1), nil
+		case fieldPending:
 			f.pending = !f.pending
 			return f, nil
 		}
 
-	case msg.Type == tea.KeyEnter && f.focusIndex == 3:
+	case f.focusIndex == fieldProfile && (msg.Type == tea.KeyEnter || msg.Type == tea.KeyRight):
+		return f.cycleProfile(1), nil
+
+	case f.focusIndex == fieldProfile && msg.Type == tea.KeyLeft:
+		return f.cycleProfile(-1), nil
+
+	case msg.Type == tea.KeyEnter && f.focusIndex == fieldPending:
 		f.pending = !f.pending
 		return f, nil
 	}
 
 	// Delegate to focused component
-	if f.focusIndex == 0 {
-		var cmd tea.Cmd
-		f.dirPicker, cmd = f.dirPicker.Update(msg)
-		return f, cmd
-	}
+	return f.updateFocused(msg)
+}
 
-	idx := f.focusIndex - 1
-	if idx >= 0 && idx < len(f.inputs) {
-		var cmd tea.Cmd
-		f.inputs[idx], cmd = f.inputs[idx].Update(msg)
+// cycleProfile moves the profile selection by delta, wrapping around.
+func (f form) cycleProfile(delta int) form {
+	n := len(f.profiles)
+	f.profile = ((f.profile+delta)%n + n) % n
+	return f
+}
 
-		if f.focusIndex == 2 {
-			f.updateImageCompletions()
+// setFocus focuses or blurs the component of the focused field.
+func (f *form) setFocus(on bool) {
+	f.completions = nil
+	switch f.focusIndex {
+	case fieldDir:
+		if on {
+			f.dirPicker.Focus()
+		} else {
+			f.dirPicker.Blur()
+		}
+	case fieldTask:
+		if on {
+			f.task.Focus()
+		} else {
+			f.task.Blur()
+		}
+	case fieldAttach, fieldContext, fieldTimeout:
+		if on {
+			f.inputs[f.focusIndex-fieldAttach].Focus()
+		} else {
+			f.inputs[f.focusIndex-fieldAttach].Blur()
 		}
-
-		return f, cmd
 	}
-	return f, nil
 }
 
 func (f form) advanceFocus() form {
-	if f.focusIndex == 0 {
-		f.dirPicker.Blur()
-	} else {
-		idx := f.focusIndex - 1
-		if idx >= 0 && idx < len(f.inputs) {
-			f.inputs[idx].Blur()
-		}
-	}
-
+	f.setFocus(false)
 	f.focusIndex++
-	if f.focusIndex > 3 {
-		f.focusIndex = 0
-	}
-
-	if f.focusIndex == 0 {
-		f.dirPicker.Focus()
-	} else {
-		idx := f.focusIndex - 1
-		if idx >= 0 && idx < len(f.inputs) {
-			f.inputs[idx].Focus()
-		}
+	if f.focusIndex > fieldPending {
+		f.focusIndex = fieldDir
 	}
+	f.setFocus(true)
 	return f
 }
 
 func (f form) retreatFocus() form {
-	if f.focusIndex == 0 {
-		f.dirPicker.Blur()
-	} else {
-		idx := f.focusIndex - 1
-		if idx >= 0 && idx < len(f.inputs) {
-			f.inputs[idx].Blur()
-		}
-	}
-
+	f.setFocus(false)
 	f.focusIndex--
 	if f.focusIndex < 0 {
-		f.focusIndex = 3
+		f.focusIndex = fieldPending
 	}
+	f.setFocus(true)
+	return f
+}
 
-	if f.focusIndex == 0 {
-		f.dirPicker.Focus()
-	} else {
-		idx := f.focusIndex - 1
-		if idx >= 0 && idx < len(f.inputs) {
-			f.inputs[idx].Focus()
+// splitList splits a comma-separated field into its non-empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
 		}
 	}
-	return f
+	return out
 }
 
-func (f *form) updateImageCompletions() {
-	val := f.inputs[1].Value()
+// updatePathCompletions lists files and directories starting with the last
+// entry of the focused attach or context field.
+func (f *form) updatePathCompletions() {
+	val := f.inputs[f.focusIndex-fieldAttach].Value()
+	if i := strings.LastIndex(val, ","); i >= 0 {
+		val = val[i+1:]
+	}
+	val = strings.TrimSpace(val)
 	if val == "" {
 		f.completions = nil
 		return
@@ -218,17 +357,21 @@ func (f *form) updateImageCompletions() {
 	}
 
 	matches, _ := filepath.Glob(expanded + "*")
-	var dirs []string
+	var paths []string
 	for _, m := range matches {
 		info, err := os.Stat(m)
-		if err == nil && info.IsDir() {
-			dirs = append(dirs, m)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			m += string(filepath.Separator)
 		}
+		paths = append(paths, m)
 	}
-	if len(dirs) > 5 {
-		dirs = dirs[:5]
+	if len(paths) > 5 {
+		paths = paths[:5]
 	}
-	f.completions = dirs
+	f.completions = paths
 }
 
 func (f form) View() string {
@@ -238,6 +381,15 @@ func (f form) View() string {
 	b.WriteString(title)
 	b.WriteString("\n\n")
 
+	if f.picker != nil {
+		b.WriteString(f.picker.View())
+		b.WriteString("\n  ")
+		b.WriteString(helpKeyStyle.Render("[Enter]") + " " + helpDescStyle.Render("choose"))
+		b.WriteString("  ")
+		b.WriteString(helpKeyStyle.Render("[Esc]") + " " + helpDescStyle.Render("back"))
+		return f.box(b.String())
+	}
+
 	// Field 0: Directory (dirpicker)
 	style := formLabelStyle
 	if f.focusIndex == 0 {
@@ -251,11 +403,26 @@ func (f form) View() string {
 		b.WriteString(f.dirPicker.CandidatesView())
 	}
 
-	// Fields 1-2: Task, Image
-	labels := []string{"Task:", "Image:"}
+	// Task (textarea, below its label)
+	taskStyle := formLabelStyle
+	if f.focusIndex == fieldTask {
+		taskStyle = taskStyle.Foreground(colorPrimary)
+	} else {
+		taskStyle = taskStyle.Foreground(colorMuted)
+	}
+	b.WriteString("  " + taskStyle.Render("Task:") + "\n")
+	for _, line := range strings.Split(f.task.View(), "\n") {
+		b.WriteString("  " + line + "\n")
+	}
+	if f.editErr != nil {
+		b.WriteString("  " + flashErrorStyle.Render("Editor: "+f.editErr.Error()) + "\n")
+	}
+
+	// Attach, Context, Timeout
+	labels := []string{"Attach:", "Context:", "Timeout:"}
 	for i, label := range labels {
 		style := formLabelStyle
-		fieldIdx := i + 1
+		fieldIdx := fieldAttach + i
 		if fieldIdx == f.focusIndex {
 			style = style.Foreground(colorPrimary)
 		} else {
@@ -264,7 +431,7 @@ func (f form) View() string {
 		b.WriteString("  " + style.Render(label) + " " + f.inputs[i].View())
 		b.WriteString("\n")
 
-		if i == 1 && f.focusIndex == 2 && len(f.completions) > 0 {
+		if fieldIdx == f.focusIndex && fieldIdx != fieldTimeout && len(f.completions) > 0 {
 			home, _ := os.UserHomeDir()
 			for _, c := range f.completions {
 				display := c
@@ -276,9 +443,26 @@ func (f form) View() string {
 		}
 	}
 
+	// Profile selector
+	profileStyle := formLabelStyle
+	if f.focusIndex == fieldProfile {
+		profileStyle = profileStyle.Foreground(colorPrimary)
+	} else {
+		profileStyle = profileStyle.Foreground(colorMuted)
+	}
+	profile := f.profiles[f.profile]
+	if profile == "" {
+		profile = "(none)"
+	}
+	if len(f.profiles) > 1 {
+		profile = "‹ " + profile + " ›"
+	}
+	b.WriteString("  " + profileStyle.Render("Profile:") + " " + profile)
+	b.WriteString("\n")
+
 	// Pending checkbox
 	checkStyle := formLabelStyle
-	if f.focusIndex == 3 {
+	if f.focusIndex == fieldPending {
 		checkStyle = checkStyle.Foreground(colorPrimary)
 	} else {
 		checkStyle = checkStyle.Foreground(colorMuted)
@@ -296,11 +480,21 @@ func (f form) View() string {
 	b.WriteString("  ")
 	b.WriteString(helpKeyStyle.Render("[↓/↑]") + " " + helpDescStyle.Render("browse"))
 	b.WriteString("  ")
+	b.WriteString(helpKeyStyle.Render("[Ctrl+E]") + " " + helpDescStyle.Render("editor"))
+	if len(f.templates) > 0 {
+		b.WriteString("  ")
+		b.WriteString(helpKeyStyle.Render("[Ctrl+T]") + " " + helpDescStyle.Render("template"))
+	}
+	b.WriteString("  ")
 	b.WriteString(helpKeyStyle.Render("[Ctrl+S]") + " " + helpDescStyle.Render("create"))
 	b.WriteString("  ")
 	b.WriteString(helpKeyStyle.Render("[Esc]") + " " + helpDescStyle.Render("cancel"))
 
-	content := b.String()
+	return f.box(b.String())
+}
+
+// box draws the form's border around content.
+func (f form) box(content string) string {
 	boxWidth := 60
 	if f.width < boxWidth+4 {
 		boxWidth = f.width - 4
@@ -1,13 +1,17 @@
 package tui
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/scottstav/wreccless/internal/templates"
 )
 
 func TestFormFieldNavigation(t *testing.T) {
-	f := newForm(80, 24, nil)
+	f := newForm(80, 24, nil, nil, nil)
 	if f.focusIndex != 0 {
 		t.Errorf("expected focus at 0, got %d", f.focusIndex)
 	}
@@ -24,7 +28,7 @@ func TestFormFieldNavigation(t *testing.T) {
 }
 
 func TestFormCancel(t *testing.T) {
-	f := newForm(80, 24, nil)
+	f := newForm(80, 24, nil, nil, nil)
 	_, cmd := f.Update(tea.KeyMsg{Type: tea.KeyEsc})
 	if cmd == nil {
 		t.Error("expected Esc to produce a cancel command")
@@ -32,8 +36,8 @@ func TestFormCancel(t *testing.T) {
 }
 
 func TestFormTogglePending(t *testing.T) {
-	f := newForm(80, 24, nil)
-	f.focusIndex = 3
+	f := newForm(80, 24, nil, nil, nil)
+	f.focusIndex = fieldPending
 	if f.pending {
 		t.Error("expected pending to start false")
 	}
@@ -44,7 +48,7 @@ func TestFormTogglePending(t *testing.T) {
 }
 
 func TestFormDirPickerIntegration(t *testing.T) {
-	f := newForm(80, 24, nil)
+	f := newForm(80, 24, nil, nil, nil)
 	if f.focusIndex != 0 {
 		t.Errorf("expected focus at 0, got %d", f.focusIndex)
 	}
@@ -54,7 +58,7 @@ func TestFormDirPickerIntegration(t *testing.T) {
 }
 
 func TestFormDirPickerNextFieldMsg(t *testing.T) {
-	f := newForm(80, 24, nil)
+	f := newForm(80, 24, nil, nil, nil)
 	f, _ = f.Update(dirPickerNextFieldMsg{})
 	if f.focusIndex != 1 {
 		t.Errorf("expected focus at 1 after dirPickerNextFieldMsg, got %d", f.focusIndex)
@@ -62,7 +66,7 @@ func TestFormDirPickerNextFieldMsg(t *testing.T) {
 }
 
 func TestFormSubmitEmpty(t *testing.T) {
-	f := newForm(80, 24, nil)
+	f := newForm(80, 24, nil, nil, nil)
 	f.dirPicker.input.SetValue("")
 	_, cmd := f.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
 	if cmd != nil {
@@ -71,12 +75,125 @@ func TestFormSubmitEmpty(t *testing.T) {
 }
 
 func TestFormWraparound(t *testing.T) {
-	f := newForm(80, 24, nil)
+	f := newForm(80, 24, nil, nil, nil)
 	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyTab}) // -> 1
 	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyTab}) // -> 2
-	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyTab}) // -> 3 (checkbox)
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyTab}) // -> 3
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyTab}) // -> 4
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyTab}) // -> 5 (profile)
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyTab}) // -> 6 (checkbox)
 	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyTab}) // -> 0 (wraparound)
 	if f.focusIndex != 0 {
 		t.Errorf("expected focus to wrap to 0, got %d", f.focusIndex)
 	}
 }
+
+func TestFormCycleProfile(t *testing.T) {
+	f := newForm(80, 24, nil, []string{"careful", "fast"}, nil)
+	f.focusIndex = fieldProfile
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyRight})
+	if got := f.profiles[f.profile]; got != "careful" {
+		t.Errorf("expected careful, got %q", got)
+	}
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	if got := f.profiles[f.profile]; got != "fast" {
+		t.Errorf("expected left to wrap to fast, got %q", got)
+	}
+}
+
+func TestFormMultilineTask(t *testing.T) {
+	f := newForm(80, 24, nil, nil, nil)
+	f.dirPicker.input.SetValue("/tmp")
+	f = f.advanceFocus() // task
+	for _, k := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("step one")},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyRunes, Runes: []rune("step two")},
+	} {
+		f, _ = f.Update(k)
+	}
+	_, cmd := f.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if cmd == nil {
+		t.Fatal("expected submit to produce a command")
+	}
+	if msg, ok := cmd().(createMsg); !ok || msg.task != "step one\nstep two" {
+		t.Errorf("expected a multi-line task, got %+v", msg)
+	}
+}
+
+func TestFormEditedTask(t *testing.T) {
+	f := newForm(80, 24, nil, nil, nil)
+	f, _ = f.Update(editedMsg{task: strings.Repeat("long spec\n", 100)})
+	if got := f.task.Value(); len(got) != 1000 {
+		t.Errorf("expected the edited task unabridged, got %d bytes", len(got))
+	}
+}
+
+func TestFormPathCompletions(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "shot.png"), nil, 0644)
+	os.Mkdir(filepath.Join(dir, "shots"), 0755)
+
+	f := newForm(80, 24, nil, nil, nil)
+	f.focusIndex = fieldContext
+	f.inputs[1].SetValue("notes.md, " + filepath.Join(dir, "sh"))
+	f.updatePathCompletions()
+	want := []string{filepath.Join(dir, "shot.png"), filepath.Join(dir, "shots") + "/"}
+	if strings.Join(f.completions, " ") != strings.Join(want, " ") {
+		t.Errorf("expected completions for the last entry %v, got %v", want, f.completions)
+	}
+	if got := splitList(" a.png, ,b.pdf "); strings.Join(got, "|") != "a.png|b.pdf" {
+		t.Errorf("splitList: %q", got)
+	}
+}
+
+func TestFormTemplatePicker(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "review.toml"), []byte(`
+description = "Review a branch"
+task = "Review branch {{.branch}} against {{.base}}"
+dir = "/tmp/proj"
+profile = "careful"
+labels = ["review"]
+
+[[vars]]
+name = "base"
+default = "main"
+`), 0644)
+	tmpls, err := templates.List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newForm(80, 24, nil, []string{"careful"}, tmpls)
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	if f.picker == nil {
+		t.Fatal("expected ctrl+t to open the template picker")
+	}
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyEnter}) // choose review
+	// Prompts follow the declared vars, then the placeholders: base, branch.
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyTab})
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("fix-login")})
+	f, cmd := f.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatalf("expected enter on the last variable to apply the template, err %v", f.picker.err)
+	}
+	f, _ = f.Update(cmd())
+
+	if f.picker != nil {
+		t.Error("expected the picker to close")
+	}
+	if got := f.task.Value(); got != "Review branch fix-login against main" {
+		t.Errorf("unexpected task %q", got)
+	}
+	if f.dirPicker.Value() != "/tmp/proj" || f.profiles[f.profile] != "careful" {
+		t.Errorf("expected dir and profile from the template, got %q, %q", f.dirPicker.Value(), f.profiles[f.profile])
+	}
+
+	_, cmd = f.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	msg := cmd().(createMsg)
+	if msg.template != "review" || len(msg.labels) != 1 || msg.labels[0] != "review" {
+		t.Errorf("expected template and labels in createMsg, got %q %v", msg.template, msg.labels)
+	}
+}
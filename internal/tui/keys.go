@@ -8,9 +8,11 @@ type dashboardKeyMap struct {
 	Enter    key.Binding
 	New      key.Binding
 	Filter   key.Binding
+	Tree     key.Binding
 	Approve  key.Binding
 	Deny     key.Binding
 	Kill     key.Binding
+	Pause    key.Binding
 	Resume   key.Binding
 	Clean    key.Binding
 	CleanAll key.Binding
@@ -39,6 +41,10 @@ var dashboardKeys = dashboardKeyMap{
 		key.WithKeys("/"),
 		key.WithHelp("/", "filter"),
 	),
+	Tree: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "tree"),
+	),
 	Approve: key.NewBinding(
 		key.WithKeys("a"),
 		key.WithHelp("a", "approve"),
@@ -51,6 +57,10 @@ var dashboardKeys = dashboardKeyMap{
 		key.WithKeys("x"),
 		key.WithHelp("x", "kill"),
 	),
+	Pause: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "pause/unpause"),
+	),
 	Resume: key.NewBinding(
 		key.WithKeys("r"),
 		key.WithHelp("r", "resume"),
@@ -84,6 +94,7 @@ type logViewKeyMap struct {
 	Approve  key.Binding
 	Deny     key.Binding
 	Kill     key.Binding
+	Pause    key.Binding
 	Resume   key.Binding
 	Clean    key.Binding
 	Quit     key.Binding
@@ -130,6 +141,10 @@ var logViewKeys = logViewKeyMap{
 		key.WithKeys("x"),
 		key.WithHelp("x", "kill"),
 	),
+	Pause: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "pause/unpause"),
+	),
 	Resume: key.NewBinding(
 		key.WithKeys("r"),
 		key.WithHelp("r", "resume"),
@@ -150,6 +165,8 @@ type formKeyMap struct {
 	Submit    key.Binding
 	Cancel    key.Binding
 	Toggle    key.Binding
+	Editor    key.Binding
+	Template  key.Binding
 }
 
 var formKeys = formKeyMap{
@@ -173,4 +190,12 @@ var formKeys = formKeyMap{
 		key.WithKeys(" "),
 		key.WithHelp("space", "toggle"),
 	),
+	Editor: key.NewBinding(
+		key.WithKeys("ctrl+e"),
+		key.WithHelp("ctrl+e", "edit task in $EDITOR"),
+	),
+	Template: key.NewBinding(
+		key.WithKeys("ctrl+t"),
+		key.WithHelp("ctrl+t", "start from a template"),
+	),
 }
@@ -19,14 +19,39 @@ type backMsg struct{}
 
 // actionMsg signals the app to perform an action on a worker.
 type actionMsg struct {
-	action string // "approve", "deny", "kill", "clean", "resume"
+	action string // "approve", "deny", "kill", "pause", "unpause", "clean", "resume"
 	worker *state.Worker
 }
 
+// killable are the statuses the kill action applies to.
+var killable = []state.Status{state.StatusWorking, state.StatusPaused, state.StatusQueued, state.StatusBlocked}
+
+// pauseAction is the action the pause key toggles to for w, or "" if it
+// can't be paused or unpaused.
+func pauseAction(w *state.Worker) string {
+	switch {
+	case w.Archived:
+		return ""
+	case w.Status == state.StatusWorking && w.PGID > 0 && w.StopRequestedAt == nil:
+		return "pause"
+	case w.Status == state.StatusPaused:
+		return "unpause"
+	}
+	return ""
+}
+
+// killedMsg reports that a kill started from an actionMsg has finished.
+type killedMsg struct {
+	id     string
+	worker *state.Worker
+	err    error
+}
+
 type logView struct {
 	stateDir   string
 	configPath string
 	worker     *state.Worker
+	events     []state.Event
 	viewport   viewport.Model
 	content    string
 	width      int
@@ -36,7 +61,7 @@ type logView struct {
 }
 
 func newLogView(stateDir, configPath string, w *state.Worker, width, height int) logView {
-	vp := viewport.New(width, height-4)
+	vp := viewport.New(width, height-5)
 	vp.SetContent("")
 
 	lv := logView{
@@ -48,6 +73,7 @@ func newLogView(stateDir, configPath string, w *state.Worker, width, height int)
 		height:     height,
 		atBottom:   true,
 	}
+	lv.events, _ = state.ReadEvents(stateDir, w.ID)
 	lv.loadLog()
 	return lv
 }
@@ -106,13 +132,17 @@ func (lv logView) handleKey(msg tea.KeyMsg) (logView, tea.Cmd) {
 			return lv, func() tea.Msg { return actionMsg{action: "deny", worker: lv.worker} }
 		}
 	case key.Matches(msg, logViewKeys.Kill):
-		if lv.worker.Status == state.StatusWorking {
+		if lv.worker.Status.In(killable) {
 			return lv, func() tea.Msg { return actionMsg{action: "kill", worker: lv.worker} }
 		}
+	case key.Matches(msg, logViewKeys.Pause):
+		if action := pauseAction(lv.worker); action != "" {
+			return lv, func() tea.Msg { return actionMsg{action: action, worker: lv.worker} }
+		}
 	case key.Matches(msg, logViewKeys.Resume):
 		return lv, func() tea.Msg { return actionMsg{action: "resume", worker: lv.worker} }
 	case key.Matches(msg, logViewKeys.Clean):
-		if lv.worker.Status == state.StatusDone || lv.worker.Status == state.StatusError {
+		if lv.worker.Status.Finished() && !lv.worker.Archived {
 			return lv, func() tea.Msg { return actionMsg{action: "clean", worker: lv.worker} }
 		}
 	}
@@ -120,8 +150,7 @@ func (lv logView) handleKey(msg tea.KeyMsg) (logView, tea.Cmd) {
 }
 
 func (lv *logView) loadLog() {
-	logPath := filepath.Join(lv.stateDir, lv.worker.ID+".log")
-	data, err := os.ReadFile(logPath)
+	data, err := state.ReadLog(lv.stateDir, lv.worker.ID)
 	if err != nil {
 		lv.content = mutedStyle.Render("No logs yet.")
 		lv.viewport.SetContent(lv.content)
@@ -133,7 +162,7 @@ func (lv *logView) loadLog() {
 		if line == "" {
 			continue
 		}
-		events := logrender.ParseLine([]byte(line))
+		events := logrender.Parser(lv.worker.LogFormat)([]byte(line))
 		for _, e := range events {
 			switch e.Type {
 			case logrender.EventText:
@@ -190,7 +219,7 @@ func (lv *logView) refreshLog() {
 		if line == "" {
 			continue
 		}
-		events := logrender.ParseLine([]byte(line))
+		events := logrender.Parser(lv.worker.LogFormat)([]byte(line))
 		for _, e := range events {
 			switch e.Type {
 			case logrender.EventText:
@@ -223,6 +252,9 @@ func (lv *logView) refreshWorker() {
 		return
 	}
 	lv.worker = w
+	if events, err := state.ReadEvents(lv.stateDir, w.ID); err == nil {
+		lv.events = events
+	}
 }
 
 func (lv logView) View() string {
@@ -242,6 +274,8 @@ func (lv logView) View() string {
 	}
 	b.WriteString(titleStyle.Render(header + strings.Repeat(" ", padding) + escHint))
 	b.WriteString("\n")
+	b.WriteString(lv.renderTimeline())
+	b.WriteString("\n")
 
 	// Separator
 	b.WriteString(mutedStyle.Render(strings.Repeat("─", lv.width)))
@@ -259,20 +293,58 @@ func (lv logView) View() string {
 	return b.String()
 }
 
+// renderTimeline renders the worker's journal as a single line, dropping the
+// oldest entries when it doesn't fit. Successful hooks are omitted.
+func (lv logView) renderTimeline() string {
+	var steps []string
+	for _, e := range lv.events {
+		if e.Type == state.EventHook && e.Error == "" {
+			continue
+		}
+		step := fmt.Sprintf("%s %s", e.Type, e.Time.Format("15:04:05"))
+		switch {
+		case e.Error != "":
+			step += " (failed)"
+		case e.ExitCode != nil:
+			step += fmt.Sprintf(" (exit %d)", *e.ExitCode)
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return mutedStyle.Render(" no history")
+	}
+	line := " " + strings.Join(steps, " → ")
+	for len(steps) > 1 && lipgloss.Width(line) > lv.width {
+		steps = steps[1:]
+		line = " … → " + strings.Join(steps, " → ")
+	}
+	return mutedStyle.Render(line)
+}
+
 func (lv logView) renderHelp() string {
 	var parts []string
 	add := func(k, desc string) {
 		parts = append(parts, helpKeyStyle.Render(k)+" "+helpDescStyle.Render(desc))
 	}
 
-	switch lv.worker.Status {
-	case state.StatusPending:
+	switch {
+	case lv.worker.Archived:
+		add("[r]", "resume")
+	case lv.worker.Status == state.StatusPending:
 		add("[a]", "approve")
 		add("[d]", "deny")
-	case state.StatusWorking:
+	case lv.worker.Status == state.StatusWorking:
 		add("[x]", "kill")
+		add("[p]", "pause")
 		add("[r]", "resume")
-	case state.StatusDone, state.StatusError:
+	case lv.worker.Status == state.StatusPaused:
+		add("[x]", "kill")
+		add("[p]", "unpause")
+	case lv.worker.Status == state.StatusQueued, lv.worker.Status == state.StatusBlocked:
+		add("[x]", "kill")
+	case lv.worker.Status == state.StatusDenied:
+		add("[c]", "clean")
+	case lv.worker.Status.Finished():
 		add("[r]", "resume")
 		add("[c]", "clean")
 	}
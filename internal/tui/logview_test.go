@@ -133,3 +133,23 @@ func TestLogViewNoLogFile(t *testing.T) {
 		t.Error("expected content to be 'No logs yet.' message, got empty")
 	}
 }
+
+func TestLogViewTimeline(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	w := &state.Worker{ID: "206", Status: state.StatusDone, Directory: "/tmp", Task: "test", CreatedAt: &now}
+	state.Write(dir, w)
+	code := 0
+	state.AppendEvent(dir, "206", state.Event{Type: state.EventCreated})
+	state.AppendEvent(dir, "206", state.Event{Type: state.EventHook, Hook: "on_done", Command: "true"})
+	state.AppendEvent(dir, "206", state.Event{Type: state.EventFinished, ExitCode: &code})
+
+	lv := newLogView(dir, "", w, 120, 24)
+	timeline := lv.renderTimeline()
+	if !containsString(timeline, "created") || !containsString(timeline, "finished") || !containsString(timeline, "(exit 0)") {
+		t.Errorf("unexpected timeline: %s", timeline)
+	}
+	if containsString(timeline, "hook") {
+		t.Errorf("successful hooks should be omitted: %s", timeline)
+	}
+}
@@ -34,6 +34,7 @@ var (
 	statusPending = lipgloss.NewStyle().Foreground(colorWarning).Bold(true)
 	statusDone    = lipgloss.NewStyle().Foreground(colorSuccess).Bold(true)
 	statusError   = lipgloss.NewStyle().Foreground(colorError).Bold(true)
+	statusStopped = lipgloss.NewStyle().Foreground(colorMuted).Bold(true)
 
 	// Log pane
 	logBorderStyle = lipgloss.NewStyle().
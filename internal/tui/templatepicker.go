@@ -0,0 +1,163 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/scottstav/wreccless/internal/templates"
+)
+
+// templateAppliedMsg carries a rendered template back to the form.
+type templateAppliedMsg struct {
+	name    string
+	task    string
+	dir     string // empty if the template has none
+	profile string
+	labels  []string
+}
+
+// templatePickerCancelMsg closes the picker without changing the form.
+type templatePickerCancelMsg struct{}
+
+// templatePicker lets the user choose a template and then prompts for each
+// of its variables.
+type templatePicker struct {
+	templates []*templates.Template
+	cursor    int
+	chosen    *templates.Template // nil while choosing
+	vars      []textinput.Model   // one per chosen.Vars
+	focus     int                 // index into vars
+	err       error               // from the last render
+}
+
+func newTemplatePicker(tmpls []*templates.Template) templatePicker {
+	return templatePicker{templates: tmpls}
+}
+
+func (p templatePicker) Update(msg tea.Msg) (templatePicker, tea.Cmd) {
+	km, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if p.chosen != nil {
+			var cmd tea.Cmd
+			p.vars[p.focus], cmd = p.vars[p.focus].Update(msg)
+			return p, cmd
+		}
+		return p, nil
+	}
+	if p.chosen == nil {
+		return p.handleChooseKey(km)
+	}
+	return p.handleVarKey(km)
+}
+
+func (p templatePicker) handleChooseKey(msg tea.KeyMsg) (templatePicker, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyDown, tea.KeyCtrlN, tea.KeyTab:
+		p.cursor = (p.cursor + 1) % len(p.templates)
+	case tea.KeyUp, tea.KeyCtrlP, tea.KeyShiftTab:
+		p.cursor = (p.cursor - 1 + len(p.templates)) % len(p.templates)
+	case tea.KeyEsc:
+		return p, func() tea.Msg { return templatePickerCancelMsg{} }
+	case tea.KeyEnter:
+		p.chosen = p.templates[p.cursor]
+		p.vars = nil
+		for _, v := range p.chosen.Vars {
+			ti := textinput.New()
+			ti.Placeholder = v.Description
+			switch {
+			case v.DefaultValue() != "":
+				ti.Placeholder = strings.TrimSpace(v.Description + " (default " + v.DefaultValue() + ")")
+			case !v.IsRequired():
+				ti.Placeholder = strings.TrimSpace(v.Description + " (optional)")
+			}
+			ti.CharLimit = 256
+			ti.Width = 40
+			p.vars = append(p.vars, ti)
+		}
+		if len(p.vars) == 0 {
+			return p.apply()
+		}
+		p.focus = 0
+		p.vars[0].Focus()
+		return p, textinput.Blink
+	}
+	return p, nil
+}
+
+func (p templatePicker) handleVarKey(msg tea.KeyMsg) (templatePicker, tea.Cmd) {
+	switch {
+	case msg.Type == tea.KeyEsc:
+		// Back to the list of templates.
+		p.chosen, p.vars, p.err = nil, nil, nil
+		return p, nil
+	case msg.Type == tea.KeyCtrlS || msg.Type == tea.KeyEnter && p.focus == len(p.vars)-1:
+		return p.apply()
+	case msg.Type == tea.KeyTab || msg.Type == tea.KeyEnter || msg.Type == tea.KeyDown:
+		return p.moveFocus(1), nil
+	case msg.Type == tea.KeyShiftTab || msg.Type == tea.KeyUp:
+		return p.moveFocus(-1), nil
+	}
+	var cmd tea.Cmd
+	p.vars[p.focus], cmd = p.vars[p.focus].Update(msg)
+	return p, cmd
+}
+
+func (p templatePicker) moveFocus(delta int) templatePicker {
+	p.vars[p.focus].Blur()
+	n := len(p.vars)
+	p.focus = ((p.focus+delta)%n + n) % n
+	p.vars[p.focus].Focus()
+	return p
+}
+
+// apply renders the chosen template with the values entered so far.
+func (p templatePicker) apply() (templatePicker, tea.Cmd) {
+	values := map[string]string{}
+	for i, v := range p.chosen.Vars {
+		if val := strings.TrimSpace(p.vars[i].Value()); val != "" {
+			values[v.Name] = val
+		}
+	}
+	task, dir, err := p.chosen.Render(values)
+	if err != nil {
+		p.err = err
+		return p, nil
+	}
+	t := p.chosen
+	return p, func() tea.Msg {
+		return templateAppliedMsg{name: t.Name, task: task, dir: dir, profile: t.Profile, labels: t.Labels}
+	}
+}
+
+func (p templatePicker) View() string {
+	var b strings.Builder
+	if p.chosen == nil {
+		for i, t := range p.templates {
+			line := t.Name
+			if t.Description != "" {
+				line += "  " + t.Description
+			}
+			if i == p.cursor {
+				b.WriteString("    " + lipgloss.NewStyle().Foreground(colorPrimary).Bold(true).Render("> "+line) + "\n")
+			} else {
+				b.WriteString("    " + mutedStyle.Render("  "+line) + "\n")
+			}
+		}
+		return b.String()
+	}
+
+	b.WriteString("  " + formLabelStyle.Foreground(colorPrimary).Render("Template:") + " " + p.chosen.Name + "\n")
+	for i, v := range p.chosen.Vars {
+		style := formLabelStyle.Foreground(colorMuted)
+		if i == p.focus {
+			style = formLabelStyle.Foreground(colorPrimary)
+		}
+		b.WriteString("  " + style.Render(v.Name+":") + " " + p.vars[i].View() + "\n")
+	}
+	if p.err != nil {
+		b.WriteString("  " + flashErrorStyle.Render(p.err.Error()) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"github.com/scottstav/wreccless/internal/git"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// captureStart records the repository state of dir before worker id's
+// agent runs, pinning the commit it starts from. It returns nil if dir is
+// not in a git repository.
+func captureStart(dir, id string) *state.GitInfo {
+	head, err := git.Head(dir)
+	if err != nil {
+		return nil
+	}
+	info := &state.GitInfo{StartHead: head, Base: head}
+	info.Branch, _ = git.Branch(dir)
+	info.StartDirty, _ = git.Dirty(dir)
+	if info.StartDirty {
+		if snap, err := git.Snapshot(dir); err == nil && snap != "" {
+			info.Base = snap
+		}
+	}
+	git.UpdateRef(dir, snapshotRef(id, "base"), info.Base)
+	return info
+}
+
+// captureEnd fills in what changed in dir since captureStart and pins the
+// commit it ends at.
+func captureEnd(dir, id string, info *state.GitInfo) {
+	head, err := git.Head(dir)
+	if err != nil {
+		return
+	}
+	info.EndHead = head
+	info.End = head
+	if snap, err := git.Snapshot(dir); err == nil && snap != "" {
+		info.End = snap
+	}
+	git.UpdateRef(dir, snapshotRef(id, "end"), info.End)
+	info.Commits, _ = git.Commits(dir, info.StartHead, head)
+	stats, _ := git.NumStat(dir, info.Base, info.End)
+	info.Files = nil
+	for _, s := range stats {
+		info.Files = append(info.Files, state.FileChange{Path: s.Path, Added: s.Added, Deleted: s.Deleted})
+	}
+	info.Untracked, _ = git.Untracked(dir)
+}
+
+// snapshotRef is the ref under which worker id's base or end commit is
+// kept. Snapshots of uncommitted work are otherwise unreferenced, and git gc
+// would eventually prune them.
+func snapshotRef(id, which string) string {
+	return "refs/ccl/" + id + "/" + which
+}
+
+// dropSnapshots deletes the refs pinning w's commits.
+func dropSnapshots(w *state.Worker) {
+	if w.Git == nil {
+		return
+	}
+	for _, which := range []string{"base", "end"} {
+		git.DeleteRef(w.Directory, snapshotRef(w.ID, which))
+	}
+}
+
+// Purge is state.Purge that also deletes the refs pinning the worker's
+// commits.
+func Purge(stateDir, id string, from []state.Status) error {
+	w, err := state.ReadAny(stateDir, id)
+	if err != nil {
+		return err
+	}
+	if err := state.Purge(stateDir, id, from); err != nil {
+		return err
+	}
+	dropSnapshots(w)
+	return nil
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestRunCapturesGitChanges(t *testing.T) {
+	stateDir := t.TempDir()
+	repo := initRepo(t)
+	// Uncommitted work from before the worker started must not be
+	// attributed to it.
+	os.WriteFile(filepath.Join(repo, "old.txt"), []byte("before\n"), 0644)
+	gitRun(t, repo, "add", "old.txt")
+
+	script := filepath.Join(t.TempDir(), "committing-claude")
+	os.WriteFile(script, []byte(`#!/bin/sh
+echo one > a.txt
+git add a.txt
+git -c user.name=t -c user.email=t@t commit -q -m "add a"
+echo two >> a.txt
+echo scratch > new.txt
+`), 0755)
+
+	state.Write(stateDir, &state.Worker{ID: "3100", Status: state.StatusWorking, Directory: repo, Task: "change", SessionID: "s"})
+	if err := Run(stateDir, "3100", config.Defaults(), script); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	g := mustRead(t, stateDir, "3100").Git
+	if g == nil {
+		t.Fatal("git info not recorded")
+	}
+	if !g.StartDirty || g.Base == g.StartHead || g.Branch == "" {
+		t.Errorf("start not captured: %+v", g)
+	}
+	if len(g.Commits) != 1 || !strings.HasSuffix(g.Commits[0], " add a") {
+		t.Errorf("commits: %v", g.Commits)
+	}
+	if len(g.Files) != 1 || g.Files[0].Path != "a.txt" || g.Files[0].Added != 2 {
+		t.Errorf("files: %+v", g.Files)
+	}
+	if len(g.Untracked) != 1 || g.Untracked[0] != "new.txt" {
+		t.Errorf("untracked: %v", g.Untracked)
+	}
+
+	// The snapshots are unreferenced commits; refs keep git gc off them.
+	for ref, want := range map[string]string{"refs/ccl/3100/base": g.Base, "refs/ccl/3100/end": g.End} {
+		if got, err := exec.Command("git", "-C", repo, "rev-parse", ref).Output(); err != nil || strings.TrimSpace(string(got)) != want {
+			t.Errorf("%s = %q, %v; want %s", ref, got, err, want)
+		}
+	}
+	if err := Purge(stateDir, "3100", state.FinishedStatuses); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if out, _ := exec.Command("git", "-C", repo, "for-each-ref", "refs/ccl/").Output(); len(out) != 0 {
+		t.Errorf("expected purge to delete the refs, got %s", out)
+	}
+}
+
+func TestRunOutsideRepoHasNoGitInfo(t *testing.T) {
+	stateDir := t.TempDir()
+	state.Write(stateDir, &state.Worker{ID: "3101", Status: state.StatusWorking, Directory: t.TempDir(), Task: "plain", SessionID: "s"})
+	Run(stateDir, "3101", config.Defaults(), "/bin/true")
+	if g := mustRead(t, stateDir, "3101").Git; g != nil {
+		t.Errorf("expected no git info, got %+v", g)
+	}
+}
+
+func mustRead(t *testing.T, dir, id string) *state.Worker {
+	t.Helper()
+	w, err := state.Read(dir, id)
+	if err != nil {
+		t.Fatalf("read %s: %v", id, err)
+	}
+	return w
+}
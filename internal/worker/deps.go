@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// DependencyActor is who stops a blocked worker whose dependencies can no
+// longer be met.
+const DependencyActor = "ccl (dependencies)"
+
+// depsVerdict reports whether blocked worker w may run: ready if its
+// dependencies are met, otherwise why they never will be, or "" if it
+// should keep waiting. Dependencies are looked up in the archive too; one
+// that no longer exists counts as failed.
+func depsVerdict(stateDir string, w *state.Worker) (ready bool, never string) {
+	want, did := "succeed", "succeeded"
+	if w.OnFailure {
+		want, did = "fail", "failed"
+	}
+	met, finished := 0, 0
+	var unmet []string
+	for _, id := range w.After {
+		status, desc := state.StatusError, "not found"
+		if d, err := state.ReadAny(stateDir, id); err == nil {
+			status, desc = d.Status, string(d.Status)
+		}
+		if !status.Finished() {
+			continue
+		}
+		finished++
+		if (status == state.StatusDone) != w.OnFailure {
+			met++
+		} else {
+			unmet = append(unmet, id+" "+desc)
+		}
+	}
+
+	if w.AfterAny {
+		switch {
+		case met > 0:
+			return true, ""
+		case finished == len(w.After):
+			return false, fmt.Sprintf("none of the dependencies %s: %s", did, strings.Join(unmet, ", "))
+		}
+		return false, ""
+	}
+	switch {
+	case len(unmet) > 0:
+		return false, fmt.Sprintf("dependency did not %s: %s", want, strings.Join(unmet, ", "))
+	case met == len(w.After):
+		return true, ""
+	}
+	return false, ""
+}
+
+// unblockLocked queues the blocked workers whose dependencies are met and
+// kills those whose dependencies never will be, repeating while that
+// settles more of them. It returns the killed workers for on_kill hooks.
+// Callers hold the scheduler lock.
+func unblockLocked(stateDir string) []*state.Worker {
+	var killed []*state.Worker
+	for {
+		workers, err := state.List(stateDir)
+		if err != nil {
+			return killed
+		}
+		changed := false
+		for _, b := range workers {
+			if b.Status != state.StatusBlocked {
+				continue
+			}
+			ready, never := depsVerdict(stateDir, b)
+			switch {
+			case ready:
+				_, err := state.Transition(stateDir, b.ID, []state.Status{state.StatusBlocked}, func(w *state.Worker) error {
+					now := time.Now()
+					w.Status = state.StatusQueued
+					w.QueuedAt = &now
+					return nil
+				})
+				if err == nil {
+					state.AppendEvent(stateDir, b.ID, state.Event{Type: state.EventUnblocked, Status: state.StatusQueued, Detail: "after " + strings.Join(b.After, ", ")})
+				}
+			case never != "":
+				w, err := state.Transition(stateDir, b.ID, []state.Status{state.StatusBlocked}, func(w *state.Worker) error {
+					now := time.Now()
+					w.Status = state.StatusKilled
+					w.FinishedAt = &now
+					w.StoppedBy = DependencyActor
+					w.ErrorReason = never
+					return nil
+				})
+				if err == nil {
+					state.AppendEvent(stateDir, b.ID, state.Event{Type: state.EventKilled, Actor: DependencyActor, Error: never})
+					killed = append(killed, w)
+					// Its own dependents may be settled by this.
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			return killed
+		}
+	}
+}
+
+// Dependents lists the workers, live or archived, that wait on worker id.
+func Dependents(stateDir, id string) ([]*state.Worker, error) {
+	workers, err := state.ListAll(stateDir)
+	if err != nil {
+		return nil, err
+	}
+	var out []*state.Worker
+	for _, w := range workers {
+		for _, dep := range w.After {
+			if dep == id {
+				out = append(out, w)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// AfterCondition describes what blocked worker w waits for, e.g. "all
+// succeed" or "any fails".
+func AfterCondition(w *state.Worker) string {
+	switch {
+	case w.AfterAny && w.OnFailure:
+		return "any fails"
+	case w.AfterAny:
+		return "any succeeds"
+	case w.OnFailure:
+		return "all fail"
+	}
+	return "all succeed"
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func block(t *testing.T, dir, id string, after []string, anyDep, onFailure bool) {
+	t.Helper()
+	w := &state.Worker{ID: id, Status: state.StatusBlocked, Directory: "/a", Task: id, SessionID: "s", After: after, AfterAny: anyDep, OnFailure: onFailure}
+	if err := state.Write(dir, w); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func finishAs(dir, id string, status state.Status) {
+	state.Transition(dir, id, nil, func(w *state.Worker) error { w.Status = status; return nil })
+}
+
+func TestPromoteDependencyChain(t *testing.T) {
+	dir := t.TempDir()
+	state.Write(dir, &state.Worker{ID: "3000", Status: state.StatusWorking, Directory: "/a"})
+	state.Write(dir, &state.Worker{ID: "3001", Status: state.StatusWorking, Directory: "/a"})
+	block(t, dir, "3002", []string{"3000", "3001"}, false, false) // fan-in
+	block(t, dir, "3003", []string{"3002"}, false, false)         // chain
+	block(t, dir, "3004", []string{"3000"}, false, true)          // on failure
+
+	cfg := config.Defaults()
+	var launched []string
+	Promote(dir, cfg, recordLaunches(&launched))
+	if len(launched) != 0 {
+		t.Fatalf("nothing should start while dependencies run, got %v", launched)
+	}
+
+	finishAs(dir, "3000", state.StatusDone)
+	Promote(dir, cfg, recordLaunches(&launched))
+	if len(launched) != 0 {
+		t.Fatalf("3002 needs both dependencies, got %v", launched)
+	}
+	// 3000 succeeded, so the on-failure worker never runs.
+	if w, _ := state.Read(dir, "3004"); w.Status != state.StatusKilled || w.StoppedBy != DependencyActor {
+		t.Errorf("expected 3004 killed by its dependencies, got %s by %q", w.Status, w.StoppedBy)
+	}
+
+	finishAs(dir, "3001", state.StatusDone)
+	Promote(dir, cfg, recordLaunches(&launched))
+	if fmt.Sprint(launched) != "[3002]" {
+		t.Fatalf("expected 3002 to start once both finished, got %v", launched)
+	}
+	if w, _ := state.Read(dir, "3003"); w.Status != state.StatusBlocked {
+		t.Errorf("3003 should wait for 3002, got %s", w.Status)
+	}
+
+	// 3002 fails; its dependent is killed rather than left blocked.
+	finishAs(dir, "3002", state.StatusError)
+	launched = nil
+	Promote(dir, cfg, recordLaunches(&launched))
+	w, _ := state.Read(dir, "3003")
+	if len(launched) != 0 || w.Status != state.StatusKilled || w.ErrorReason != "dependency did not succeed: 3002 error" {
+		t.Errorf("expected 3003 killed, got %s (%q), launched %v", w.Status, w.ErrorReason, launched)
+	}
+}
+
+func TestPromoteAfterAny(t *testing.T) {
+	dir := t.TempDir()
+	state.Write(dir, &state.Worker{ID: "3100", Status: state.StatusWorking, Directory: "/a"})
+	state.Write(dir, &state.Worker{ID: "3101", Status: state.StatusWorking, Directory: "/a"})
+	block(t, dir, "3102", []string{"3100", "3101"}, true, false)
+	block(t, dir, "3103", []string{"3100", "3101"}, true, true)
+
+	cfg := config.Defaults()
+	var launched []string
+	finishAs(dir, "3100", state.StatusError)
+	Promote(dir, cfg, recordLaunches(&launched))
+	if fmt.Sprint(launched) != "[3103]" {
+		t.Fatalf("expected the any-failure worker to start, got %v", launched)
+	}
+
+	finishAs(dir, "3101", state.StatusTimeout)
+	Promote(dir, cfg, recordLaunches(&launched))
+	w, _ := state.Read(dir, "3102")
+	if w.Status != state.StatusKilled || w.ErrorReason != "none of the dependencies succeeded: 3100 error, 3101 timeout" {
+		t.Errorf("expected 3102 killed once every dependency failed, got %s (%q)", w.Status, w.ErrorReason)
+	}
+}
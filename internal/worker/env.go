@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// Environ returns the environment w's agent runs with: ccl's own, then
+// [claude.env] from cfg, w's env files in order, w's own Env, and last the
+// CCL_* variables identifying the worker. Later entries win.
+func Environ(stateDir string, w *state.Worker, cfg *config.Config) ([]string, error) {
+	out := os.Environ()
+	add := func(env map[string]string) {
+		for _, k := range slices.Sorted(maps.Keys(env)) {
+			out = append(out, k+"="+env[k])
+		}
+	}
+	add(cfg.Claude.Env)
+	for _, path := range w.EnvFiles {
+		env, err := ReadEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		add(env)
+	}
+	add(w.Env)
+
+	if abs, err := filepath.Abs(stateDir); err == nil {
+		stateDir = abs
+	}
+	return append(out,
+		"CCL_WORKER_ID="+w.ID,
+		"CCL_STATE_DIR="+stateDir,
+		"CCL_SESSION_ID="+w.SessionID,
+	), nil
+}
+
+// ParseEnv splits a KEY=VALUE assignment.
+func ParseEnv(s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", fmt.Errorf("invalid env %q: want KEY=VALUE", s)
+	}
+	return key, value, nil
+}
+
+// ReadEnvFile reads a dotenv-style file: KEY=VALUE lines, optionally
+// prefixed with "export " and with the value in single or double quotes.
+// Blank lines and lines starting with # are skipped.
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("env file: %w", err)
+	}
+	defer f.Close()
+
+	env := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, err := ParseEnv(strings.TrimPrefix(line, "export "))
+		if err != nil {
+			return nil, fmt.Errorf("env file %s:%d: %w", path, n, err)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("env file %s: %w", path, err)
+	}
+	return env, nil
+}
@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestReadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.env")
+	os.WriteFile(path, []byte("# comment\n\nexport TOKEN=\"s3cret\"\nURL='http://x?a=b'\nPLAIN = v \n"), 0600)
+	env, err := ReadEnvFile(path)
+	if err != nil {
+		t.Fatalf("ReadEnvFile: %v", err)
+	}
+	if env["TOKEN"] != "s3cret" || env["URL"] != "http://x?a=b" || env["PLAIN"] != "v" || len(env) != 3 {
+		t.Errorf("unexpected env %v", env)
+	}
+
+	os.WriteFile(path, []byte("OK=1\nnot an assignment\n"), 0600)
+	if _, err := ReadEnvFile(path); err == nil {
+		t.Error("expected an error for a line without =")
+	}
+}
+
+func TestEnvironPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.env")
+	os.WriteFile(file, []byte("A=file\nB=file\n"), 0600)
+	cfg := config.Defaults()
+	cfg.Claude.Env = map[string]string{"A": "config", "B": "config", "C": "config"}
+	w := &state.Worker{ID: "1030", SessionID: "sess", EnvFiles: []string{file}, Env: map[string]string{"A": "flag", "CCL_WORKER_ID": "spoof"}}
+
+	env, err := Environ("/tmp/state", w, cfg)
+	if err != nil {
+		t.Fatalf("Environ: %v", err)
+	}
+	last := func(key string) string {
+		v := ""
+		for _, kv := range env {
+			if k, val, _ := ParseEnv(kv); k == key {
+				v = val
+			}
+		}
+		return v
+	}
+	for key, want := range map[string]string{
+		"A": "flag", "B": "file", "C": "config",
+		"CCL_WORKER_ID": "1030", "CCL_STATE_DIR": "/tmp/state", "CCL_SESSION_ID": "sess",
+	} {
+		if got := last(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if !slices.Contains(env, "PATH="+os.Getenv("PATH")) {
+		t.Error("expected ccl's own environment to be inherited")
+	}
+
+	w.EnvFiles = []string{filepath.Join(t.TempDir(), "missing.env")}
+	if _, err := Environ("/tmp/state", w, cfg); err == nil {
+		t.Error("expected an error for a missing env file")
+	}
+}
@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// RetentionPolicy converts the [retention] config section to a state.Policy.
+// Only finished workers are removed, so max_age keys must be finished
+// statuses.
+func RetentionPolicy(r config.RetentionConfig) (state.Policy, error) {
+	p := state.Policy{
+		MaxAge:      map[state.Status]time.Duration{},
+		MaxLogBytes: r.MaxLogBytes,
+		KeepLast:    r.KeepLast,
+	}
+	for status, d := range r.MaxAge {
+		if !slices.Contains(state.FinishedStatuses, state.Status(status)) {
+			return state.Policy{}, fmt.Errorf("retention.max_age: unknown status %q (want one of %s)", status, statusNames(state.FinishedStatuses))
+		}
+		p.MaxAge[state.Status(status)] = d.Duration
+	}
+	return p, nil
+}
+
+func statusNames(set []state.Status) string {
+	names := make([]string, len(set))
+	for i, s := range set {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
+
+// GC applies the configured retention policy to the state dir and returns
+// what it removed. The workers in keep are never removed. With dryRun set
+// nothing is deleted.
+func GC(stateDir string, cfg *config.Config, dryRun bool, keep ...string) ([]state.Removal, error) {
+	policy, err := RetentionPolicy(cfg.Retention)
+	if err != nil {
+		return nil, err
+	}
+	removals, err := state.PlanGC(stateDir, policy, time.Now(), keep...)
+	if err != nil || dryRun {
+		return removals, err
+	}
+	var removed []state.Removal
+	for _, r := range removals {
+		// A worktree with uncommitted work keeps its worker until next time.
+		if r.Worker.WorktreePath != "" && RemoveWorktree(stateDir, r.Worker, false) != nil {
+			continue
+		}
+		if err := Purge(stateDir, r.Worker.ID, state.FinishedStatuses); err != nil {
+			return removed, err
+		}
+		removed = append(removed, r)
+	}
+	return removed, nil
+}
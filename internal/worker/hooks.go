@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"strconv"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/hooks"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// FireHooks runs the commands configured for hook (e.g. "on_done") and
+// records each command's outcome in the worker's journal.
+func FireHooks(stateDir, hook string, cmds []string, vars hooks.Vars) {
+	for _, r := range hooks.Fire(cmds, vars) {
+		e := state.Event{Type: state.EventHook, Hook: hook, Command: r.Cmd}
+		if r.Err != nil {
+			e.Error = r.Err.Error()
+		}
+		state.AppendEvent(stateDir, vars.ID, e)
+	}
+}
+
+// ConfigFor returns the config w runs with: cfg merged with the project
+// files of w's directory, then w's profile applied. It falls back to cfg if
+// those no longer load.
+func ConfigFor(cfg *config.Config, w *state.Worker) *config.Config {
+	if c, err := workerConfig(cfg, w); err == nil {
+		return c
+	}
+	return cfg
+}
+
+func workerConfig(cfg *config.Config, w *state.Worker) (*config.Config, error) {
+	c, err := cfg.ForDir(w.Directory)
+	if err != nil {
+		return nil, err
+	}
+	return c.WithProfile(w.Profile)
+}
+
+// HookVars returns the template variables hooks see for w.
+func HookVars(w *state.Worker) hooks.Vars {
+	vars := hooks.Vars{
+		ID:            w.ID,
+		Task:          w.Task,
+		Dir:           w.Directory,
+		Status:        string(w.Status),
+		SessionID:     w.SessionID,
+		Signal:        w.Signal,
+		ErrorReason:   w.ErrorReason,
+		ResultSubtype: w.ResultSubtype,
+		CostUSD:       w.CostUSD,
+		NumTurns:      w.NumTurns,
+		InputTokens:   w.InputTokens,
+		OutputTokens:  w.OutputTokens,
+		DurationMS:    w.DurationMS,
+		Attempt:       max(w.Attempt, 1),
+	}
+	if w.ExitCode != nil {
+		vars.ExitCode = strconv.Itoa(*w.ExitCode)
+	}
+	return vars
+}
@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+	"golang.org/x/sys/unix"
+)
+
+// pollInterval is how often Kill checks whether a worker's processes are gone.
+const pollInterval = 50 * time.Millisecond
+
+// Kill stops worker id on behalf of actor. A queued or blocked worker is
+// marked killed straight away. For a working or paused one the stop is
+// recorded first, so that its runner finishes it as killed, then its runner
+// and claude's process group get SIGTERM and, if still alive after grace,
+// SIGKILL; force sends SIGKILL at once. Kill returns once the processes are
+// gone and the worker is killed. Hooks are left to the caller.
+func Kill(stateDir, id, actor string, grace time.Duration, force bool) (*state.Worker, error) {
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	w, err := state.Transition(stateDir, id, []state.Status{state.StatusWorking, state.StatusPaused, state.StatusQueued, state.StatusBlocked}, func(w *state.Worker) error {
+		now := time.Now()
+		w.StoppedBy = actor
+		if w.Status == state.StatusQueued || w.Status == state.StatusBlocked {
+			w.Status = state.StatusKilled
+			w.FinishedAt = &now
+			return nil
+		}
+		if w.StopRequestedAt == nil {
+			w.StopRequestedAt = &now
+		}
+		w.KillSignal = unix.SignalName(sig)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if w.Status == state.StatusKilled {
+		state.AppendEvent(stateDir, id, state.Event{Type: state.EventKilled, Actor: actor, Detail: "not started"})
+		return w, nil
+	}
+
+	state.AppendEvent(stateDir, id, state.Event{Type: state.EventKilled, PID: w.PID, Actor: actor, Detail: unix.SignalName(sig)})
+	signalAll(w, sig)
+	if w.Status == state.StatusPaused {
+		// A stopped process can't act on SIGTERM until it is continued.
+		Signal(w, syscall.SIGCONT)
+	}
+	if !force && !waitExit(stateDir, w, grace) {
+		// Only escalate while the runner hasn't finished the worker; once
+		// it has, SIGTERM did its job.
+		_, err := state.Transition(stateDir, id, []state.Status{state.StatusWorking, state.StatusPaused}, func(w *state.Worker) error {
+			w.KillSignal = "SIGKILL"
+			return nil
+		})
+		if err == nil {
+			state.AppendEvent(stateDir, id, state.Event{Type: state.EventKilled, PID: w.PID, Actor: actor, Detail: "SIGKILL after " + grace.String()})
+			signalAll(w, syscall.SIGKILL)
+		}
+	}
+	// SIGKILL can't be ignored, but reaping and the runner's own bookkeeping
+	// take a moment.
+	waitExit(stateDir, w, time.Second)
+
+	// The runner normally records the kill as it exits; do it here if it
+	// died first or there never was one.
+	now := time.Now()
+	return state.Transition(stateDir, id, nil, func(w *state.Worker) error {
+		if w.Status == state.StatusWorking || w.Status == state.StatusPaused {
+			w.PausedMS = w.PausedFor(now).Milliseconds()
+			w.PausedAt = nil
+			w.Status = state.StatusKilled
+			w.FinishedAt = &now
+		}
+		return nil
+	})
+}
+
+// signalAll sends sig to w's runner and claude's process group. The runner
+// is signalled as a group when it leads one, as it does when started by
+// SpawnRun, which takes hook commands it started along with it.
+func signalAll(w *state.Worker, sig syscall.Signal) {
+	if w.RunnerPID > 0 && w.RunnerPID != w.PGID {
+		if pgid, err := syscall.Getpgid(w.RunnerPID); err == nil && pgid == w.RunnerPID {
+			syscall.Kill(-w.RunnerPID, sig)
+		} else {
+			syscall.Kill(w.RunnerPID, sig)
+		}
+	}
+	Signal(w, sig)
+}
+
+// waitExit waits up to d for w's runner and claude's process group to be
+// gone, or for the runner to have finished w, and reports whether either
+// happened.
+func waitExit(stateDir string, w *state.Worker, d time.Duration) bool {
+	deadline := time.Now().Add(d)
+	for {
+		if !running(w) || finished(stateDir, w) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// finished reports whether w's state on disk shows it finished, or its
+// runner cleared. A runner may linger a little after finishing, to run
+// hooks, without that holding up a kill.
+func finished(stateDir string, w *state.Worker) bool {
+	cur, err := state.Read(stateDir, w.ID)
+	if err != nil {
+		return errors.Is(err, fs.ErrNotExist)
+	}
+	return cur.Status.Finished() || (w.RunnerPID > 0 && cur.RunnerPID == 0)
+}
+
+func running(w *state.Worker) bool {
+	if w.RunnerPID > 0 && isAlive(w.RunnerPID) {
+		return true
+	}
+	switch {
+	case w.PGID > 0:
+		return syscall.Kill(-w.PGID, 0) == nil
+	case w.PID > 0:
+		return isAlive(w.PID)
+	}
+	return false
+}
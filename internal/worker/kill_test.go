@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// startGroup starts a process that ignores SIGTERM in its own process group,
+// and reaps it in the background.
+func startGroup(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", "trap '' TERM; exec sleep 30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	go cmd.Wait()
+	// Don't signal it before the trap is set up and sleep is running.
+	comm := fmt.Sprintf("/proc/%d/comm", cmd.Process.Pid)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if b, _ := os.ReadFile(comm); strings.TrimSpace(string(b)) == "sleep" {
+			break
+		}
+	}
+	t.Cleanup(func() { syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) })
+	return cmd.Process.Pid
+}
+
+func TestKillEscalates(t *testing.T) {
+	dir := t.TempDir()
+	pgid := startGroup(t)
+	state.Write(dir, &state.Worker{ID: "3000", Status: state.StatusWorking, Directory: "/tmp", PID: pgid, PGID: pgid})
+
+	w, err := Kill(dir, "3000", "test", 200*time.Millisecond, false)
+	if err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+	if w.Status != state.StatusKilled || w.KillSignal != "SIGKILL" || w.StopRequestedAt == nil {
+		t.Errorf("expected killed by SIGKILL, got %+v", w)
+	}
+	if syscall.Kill(-pgid, 0) == nil {
+		t.Error("process group survived the kill")
+	}
+}
+
+func TestKillForce(t *testing.T) {
+	dir := t.TempDir()
+	pgid := startGroup(t)
+	state.Write(dir, &state.Worker{ID: "3001", Status: state.StatusWorking, Directory: "/tmp", PID: pgid, PGID: pgid})
+
+	start := time.Now()
+	w, err := Kill(dir, "3001", "test", time.Minute, true)
+	if err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("--force should not wait for the grace period")
+	}
+	if w.Status != state.StatusKilled || w.KillSignal != "SIGKILL" {
+		t.Errorf("expected killed by SIGKILL, got %+v", w)
+	}
+}
+
+func TestKillRequiresLive(t *testing.T) {
+	dir := t.TempDir()
+	state.Write(dir, &state.Worker{ID: "3002", Status: state.StatusDone, Directory: "/tmp"})
+	if _, err := Kill(dir, "3002", "test", time.Second, false); err == nil {
+		t.Error("expected an error killing a finished worker")
+	}
+}
+
+func TestKillZombieRunner(t *testing.T) {
+	dir := t.TempDir()
+	// Left unreaped, as a runner spawned by a long-lived parent was.
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill(); cmd.Wait() })
+	state.Write(dir, &state.Worker{ID: "3003", Status: state.StatusWorking, Directory: "/tmp", RunnerPID: cmd.Process.Pid})
+
+	start := time.Now()
+	w, err := Kill(dir, "3003", "test", time.Minute, false)
+	if err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Kill waited on a zombie runner")
+	}
+	if w.Status != state.StatusKilled || w.KillSignal != "SIGTERM" {
+		t.Errorf("expected killed by SIGTERM, got %+v", w)
+	}
+}
+
+func TestKillNoEscalationAfterFinish(t *testing.T) {
+	dir := t.TempDir()
+	pgid := startGroup(t)
+	state.Write(dir, &state.Worker{ID: "3004", Status: state.StatusWorking, Directory: "/tmp", RunnerPID: pgid})
+	// The runner finishes the worker but lingers, as it does running hooks.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		state.Transition(dir, "3004", nil, func(w *state.Worker) error {
+			now := time.Now()
+			w.Status = state.StatusKilled
+			w.FinishedAt = &now
+			return nil
+		})
+	}()
+
+	start := time.Now()
+	w, err := Kill(dir, "3004", "test", time.Minute, false)
+	if err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Kill waited out the grace period after the worker finished")
+	}
+	if w.KillSignal != "SIGTERM" {
+		t.Errorf("KillSignal rewritten after finish: %+v", w)
+	}
+	events, _ := state.ReadEvents(dir, "3004")
+	for _, e := range events {
+		if strings.HasPrefix(e.Detail, "SIGKILL after") {
+			t.Errorf("escalation journaled after finish: %+v", e)
+		}
+	}
+}
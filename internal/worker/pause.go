@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// Pause stops worker id's claude process group with SIGSTOP and marks it
+// paused. The runner keeps going, so the worker can still be killed or time
+// out; time spent paused doesn't count towards its timeout.
+func Pause(stateDir, id, actor string) (*state.Worker, error) {
+	w, err := state.Transition(stateDir, id, []state.Status{state.StatusWorking}, func(w *state.Worker) error {
+		if w.PGID == 0 {
+			return fmt.Errorf("worker %s has no running process to pause", w.ID)
+		}
+		if w.StopRequestedAt != nil {
+			return fmt.Errorf("worker %s is being stopped", w.ID)
+		}
+		now := time.Now()
+		w.Status = state.StatusPaused
+		w.PausedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := Signal(w, syscall.SIGSTOP); err != nil {
+		// Nothing was stopped; don't leave it looking paused.
+		state.Transition(stateDir, id, []state.Status{state.StatusPaused}, func(w *state.Worker) error {
+			w.Status = state.StatusWorking
+			w.PausedAt = nil
+			return nil
+		})
+		return nil, fmt.Errorf("pause worker %s: %w", id, err)
+	}
+	state.AppendEvent(stateDir, id, state.Event{Type: state.EventPaused, PID: w.PGID, Actor: actor})
+	return w, nil
+}
+
+// Unpause continues a paused worker with SIGCONT and adds the time it spent
+// paused to PausedMS.
+func Unpause(stateDir, id, actor string) (*state.Worker, error) {
+	var paused time.Duration
+	w, err := state.Transition(stateDir, id, []state.Status{state.StatusPaused}, func(w *state.Worker) error {
+		paused = w.PausedFor(time.Now()) - time.Duration(w.PausedMS)*time.Millisecond
+		w.PausedMS += paused.Milliseconds()
+		w.PausedAt = nil
+		w.Status = state.StatusWorking
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	Signal(w, syscall.SIGCONT)
+	state.AppendEvent(stateDir, id, state.Event{Type: state.EventUnpaused, PID: w.PGID, Actor: actor, Detail: "paused " + paused.Round(time.Second).String()})
+	return w, nil
+}
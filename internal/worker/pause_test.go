@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// procState is the one-letter state of pid from /proc, e.g. "S" or "T".
+func procState(t *testing.T, pid int) string {
+	t.Helper()
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		t.Skipf("no /proc: %v", err)
+	}
+	// The state follows the parenthesised command name.
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return fields[0]
+}
+
+func TestPauseUnpause(t *testing.T) {
+	dir := t.TempDir()
+	pgid := startGroup(t)
+	state.Write(dir, &state.Worker{ID: "3100", Status: state.StatusWorking, Directory: "/tmp", PID: pgid, PGID: pgid})
+
+	w, err := Pause(dir, "3100", "test")
+	if err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if w.Status != state.StatusPaused || w.PausedAt == nil {
+		t.Errorf("expected paused, got %+v", w)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if s := procState(t, pgid); s != "T" {
+		t.Errorf("expected process to be stopped, state %s", s)
+	}
+	if _, err := Pause(dir, "3100", "test"); err == nil {
+		t.Error("expected an error pausing a paused worker")
+	}
+
+	w, err = Unpause(dir, "3100", "test")
+	if err != nil {
+		t.Fatalf("Unpause: %v", err)
+	}
+	if w.Status != state.StatusWorking || w.PausedAt != nil || w.PausedMS < 50 {
+		t.Errorf("expected working with paused time recorded, got %+v", w)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if s := procState(t, pgid); s == "T" {
+		t.Error("expected process to be continued")
+	}
+}
+
+func TestPauseNeedsProcess(t *testing.T) {
+	dir := t.TempDir()
+	state.Write(dir, &state.Worker{ID: "3101", Status: state.StatusWorking, Directory: "/tmp"})
+	if _, err := Pause(dir, "3101", "test"); err == nil {
+		t.Error("expected an error pausing a worker with no process")
+	}
+}
+
+func TestTimeLeftExcludesPausedTime(t *testing.T) {
+	dir := t.TempDir()
+	begin := time.Now().Add(-time.Hour)
+	pausedAt := time.Now().Add(-10 * time.Minute)
+	state.Write(dir, &state.Worker{ID: "3102", Status: state.StatusPaused, Directory: "/tmp", PausedMS: (40 * time.Minute).Milliseconds(), PausedAt: &pausedAt})
+
+	// An hour in, 50 minutes of it paused: 10 of 30 minutes used.
+	left := timeLeft(dir, "3102", begin, 30*time.Minute)
+	if left < 19*time.Minute || left > 21*time.Minute {
+		t.Errorf("expected about 20m left, got %s", left)
+	}
+	if left := timeLeft(dir, "3102", begin, 5*time.Minute); left != pausedPoll {
+		t.Errorf("a paused worker past its timeout should be rechecked later, got %s", left)
+	}
+}
+
+func TestKillPaused(t *testing.T) {
+	dir := t.TempDir()
+	pgid := startGroup(t)
+	state.Write(dir, &state.Worker{ID: "3103", Status: state.StatusWorking, Directory: "/tmp", PID: pgid, PGID: pgid})
+	if _, err := Pause(dir, "3103", "test"); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	w, err := Kill(dir, "3103", "test", 200*time.Millisecond, false)
+	if err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+	if w.Status != state.StatusKilled || w.PausedAt != nil {
+		t.Errorf("expected killed, got %+v", w)
+	}
+}
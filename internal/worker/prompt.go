@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// contextMaxBytes bounds each context file included in a prompt. Bigger
+// files should be attached so the agent reads what it needs.
+const contextMaxBytes = 256 << 10
+
+// ResolveAttachments checks that each path is an existing file and returns
+// them as absolute paths. A leading ~/ is expanded.
+func ResolveAttachments(paths []string) ([]string, error) {
+	var out []string
+	for _, p := range paths {
+		abs, err := absPath(p)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("attachment: %w", err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("attachment %s is not a file", p)
+		}
+		out = append(out, abs)
+	}
+	return out, nil
+}
+
+// ResolveContext expands each pattern, a file or a glob, to the files it
+// matches as absolute paths, dropping duplicates. A pattern that matches no
+// file is an error.
+func ResolveContext(patterns []string) ([]string, error) {
+	var out []string
+	for _, pattern := range patterns {
+		abs, err := absPath(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := filepath.Glob(abs)
+		if err != nil {
+			return nil, fmt.Errorf("context %q: %w", pattern, err)
+		}
+		n := 0
+		for _, m := range matches {
+			if info, err := os.Stat(m); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			n++
+			if !slices.Contains(out, m) {
+				out = append(out, m)
+			}
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("context %q matches no files", pattern)
+		}
+	}
+	return out, nil
+}
+
+func absPath(p string) (string, error) {
+	if strings.HasPrefix(p, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		p = filepath.Join(home, p[2:])
+	}
+	return filepath.Abs(p)
+}
+
+// Prompt is what w's agent is first given: task, after a list of w's
+// attachments to read and the contents of its context files.
+func Prompt(w *state.Worker, task string) (string, error) {
+	var b strings.Builder
+	if len(w.Attachments) > 0 {
+		b.WriteString("Read and reference these attached files:\n")
+		for _, p := range w.Attachments {
+			b.WriteString("- " + p + "\n")
+		}
+		b.WriteString("\n")
+	}
+	if len(w.Context) > 0 {
+		b.WriteString("Context files:\n\n")
+		for _, p := range w.Context {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return "", fmt.Errorf("context: %w", err)
+			}
+			if len(data) > contextMaxBytes {
+				return "", fmt.Errorf("context file %s is too big (%d bytes); attach it instead", p, len(data))
+			}
+			fmt.Fprintf(&b, "<file path=%q>\n%s", p, data)
+			if len(data) > 0 && data[len(data)-1] != '\n' {
+				b.WriteString("\n")
+			}
+			b.WriteString("</file>\n\n")
+		}
+	}
+	b.WriteString(task)
+	return b.String(), nil
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestResolveContext(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "b.md", "c.txt"} {
+		os.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+	}
+	os.Mkdir(filepath.Join(dir, "sub.md"), 0755)
+
+	got, err := ResolveContext([]string{filepath.Join(dir, "*.md"), filepath.Join(dir, "a.md")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if _, err := ResolveContext([]string{filepath.Join(dir, "*.go")}); err == nil {
+		t.Error("expected an error for a pattern matching nothing")
+	}
+	if _, err := ResolveAttachments([]string{filepath.Join(dir, "sub.md")}); err == nil {
+		t.Error("expected an error attaching a directory")
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	dir := t.TempDir()
+	notes := filepath.Join(dir, "notes.md")
+	os.WriteFile(notes, []byte("use tabs"), 0644)
+
+	w := &state.Worker{Attachments: []string{"/tmp/mock.png"}, Context: []string{notes}}
+	got, err := Prompt(w, "fix the layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"- /tmp/mock.png\n", "<file path=\"" + notes + "\">\nuse tabs\n</file>"} {
+		if !strings.Contains(got, s) {
+			t.Errorf("expected prompt to contain %q, got:\n%s", s, got)
+		}
+	}
+	if !strings.HasSuffix(got, "fix the layout") {
+		t.Errorf("expected the task last, got:\n%s", got)
+	}
+
+	os.WriteFile(notes, make([]byte, contextMaxBytes+1), 0644)
+	if _, err := Prompt(w, "x"); err == nil {
+		t.Error("expected an error for an oversized context file")
+	}
+}
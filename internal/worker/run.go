@@ -1,112 +1,502 @@
 package worker
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/scottstav/wreccless/internal/config"
-	"github.com/scottstav/wreccless/internal/hooks"
+	"github.com/scottstav/wreccless/internal/logrender"
 	"github.com/scottstav/wreccless/internal/state"
+	"golang.org/x/sys/unix"
 )
 
-// Run executes a worker's claude session. This is a blocking call.
-// claudeBin allows overriding the claude binary path for testing.
-// Pass "" to use the default "claude" from PATH.
-func Run(stateDir, id string, cfg *config.Config, claudeBin string) error {
-	w, err := state.Read(stateDir, id)
+// Run executes a worker's agent session, claude unless the worker names
+// another runner. This is a blocking call. bin allows overriding the
+// runner's program for testing; pass "" to use its own from PATH.
+func Run(stateDir, id string, cfg *config.Config, bin string) error {
+	// Claim the worker so a second "ccl run" for the same ID refuses to start.
+	w, err := state.Transition(stateDir, id, []state.Status{state.StatusWorking}, func(w *state.Worker) error {
+		if w.RunnerPID > 0 && w.RunnerPID != os.Getpid() && isAlive(w.RunnerPID) {
+			return fmt.Errorf("worker %s is already running (pid %d)", id, w.RunnerPID)
+		}
+		w.RunnerPID = os.Getpid()
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("read worker: %w", err)
+		return fmt.Errorf("claim worker: %w", err)
 	}
+	state.AppendEvent(stateDir, id, state.Event{Type: state.EventStarted, PID: os.Getpid(), Detail: "runner"})
 
-	if claudeBin == "" {
-		claudeBin = "claude"
+	if w.Task, err = state.ReadTask(stateDir, w); err != nil {
+		finish(stateDir, id, state.StatusError, cfg, outcome{ErrorReason: err.Error()})
+		return err
 	}
-
-	// Build claude arguments — hardcoded flags that ccl depends on
-	args := []string{
-		"-p",
-		"--output-format", "stream-json",
-		"--verbose",
-		"--session-id", w.SessionID,
+	prompt, err := Prompt(w, w.Task)
+	if err != nil {
+		finish(stateDir, id, state.StatusError, cfg, outcome{ErrorReason: err.Error()})
+		return err
 	}
-	if cfg.Claude.SkipPermissions {
-		args = append(args, "--dangerously-skip-permissions")
+	wcfg, err := workerConfig(cfg, w)
+	if err != nil {
+		finish(stateDir, id, state.StatusError, cfg, outcome{ErrorReason: err.Error()})
+		return err
 	}
-	if cfg.Claude.SystemPrompt != "" {
-		args = append(args, "--append-system-prompt", cfg.Claude.SystemPrompt)
+	cfg = wcfg
+	agent, err := RunnerFor(cfg, w.Runner)
+	if err != nil {
+		finish(stateDir, id, state.StatusError, cfg, outcome{ErrorReason: err.Error()})
+		return err
 	}
-	args = append(args, cfg.Claude.ExtraFlags...)
-
-	// Build task text (prepend image reference if set)
-	task := w.Task
-	if w.Image != "" {
-		task = fmt.Sprintf("Read and reference this image: %s\n\n%s", w.Image, task)
+	env, err := Environ(stateDir, w, cfg)
+	if err != nil {
+		finish(stateDir, id, state.StatusError, cfg, outcome{ErrorReason: err.Error()})
+		return err
+	}
+	// Readers of the log need to know how to parse it.
+	if w.LogFormat != agent.LogFormat() {
+		state.Transition(stateDir, id, nil, func(w *state.Worker) error {
+			w.LogFormat = agent.LogFormat()
+			return nil
+		})
+		w.LogFormat = agent.LogFormat()
 	}
-	args = append(args, task)
 
 	// Open log file
 	logPath := filepath.Join(stateDir, id+".log")
 	logFile, err := os.Create(logPath)
 	if err != nil {
+		finish(stateDir, id, state.StatusError, cfg, outcome{ErrorReason: "create log: " + err.Error()})
 		return fmt.Errorf("create log: %w", err)
 	}
 	defer logFile.Close()
+	for _, msg := range cfg.Ignored() {
+		fmt.Fprintf(logFile, "--- ccl: %s; ignored ---\n", msg)
+	}
 
-	// Build and start command
-	cmd := exec.Command(claudeBin, args...)
-	cmd.Dir = w.Directory
-	cmd.Stdout = logFile
-	cmd.Stderr = logFile
-	cmd.Stdin = nil
+	dir := w.Directory
+	if w.Isolation == IsolationWorktree {
+		dir, err = setupWorktree(stateDir, w)
+		if err != nil {
+			finish(stateDir, id, state.StatusError, cfg, outcome{ErrorReason: "worktree: " + err.Error()})
+			return fmt.Errorf("worktree: %w", err)
+		}
+	}
+
+	gitInfo := w.Git
+	if gitInfo == nil {
+		gitInfo = captureStart(dir, id)
+		if gitInfo != nil {
+			state.Transition(stateDir, id, nil, func(w *state.Worker) error {
+				w.Git = gitInfo
+				return nil
+			})
+		}
+	}
+
+	grace := cfg.Worker.KillGrace.Duration
+
+	// stop is closed once the runner is told to stop, by SIGTERM or the
+	// timeout; the current attempt is terminated and no more are made.
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var current atomic.Pointer[proc]
 
-	// Forward SIGTERM to child
+	// Forward SIGTERM to claude's process group
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
 	go func() {
 		<-sigCh
-		if cmd.Process != nil {
-			cmd.Process.Signal(syscall.SIGTERM)
+		stopOnce.Do(func() { close(stop) })
+		if p := current.Load(); p != nil {
+			syscall.Kill(-p.pgid, syscall.SIGTERM)
 		}
 	}()
 
+	// settled is set by whichever comes first, the timeout or the end of
+	// the last attempt; a timeout that fires after that doesn't apply.
+	var timedOut, settled atomic.Bool
+	var timer *time.Timer
+	timeout := time.Duration(w.TimeoutMS) * time.Millisecond
+	if timeout > 0 {
+		// The timeout bounds the worker's whole lifetime, retries included
+		// but time spent paused not.
+		begin := time.Now()
+		timer = time.NewTimer(timeout)
+		defer timer.Stop()
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-timer.C:
+				case <-done:
+					return
+				}
+				if left := timeLeft(stateDir, id, begin, timeout); left > 0 {
+					timer.Reset(left)
+					continue
+				}
+				if !settled.CompareAndSwap(false, true) {
+					return
+				}
+				timedOut.Store(true)
+				stopOnce.Do(func() { close(stop) })
+				e := state.Event{Type: state.EventTimeout, Detail: fmt.Sprintf("after %s, grace %s", timeout, grace)}
+				p := current.Load()
+				if p != nil {
+					e.PID = p.pgid
+				}
+				state.AppendEvent(stateDir, id, e)
+				if p != nil {
+					terminateGroup(p.pgid, grace, p.exited)
+				}
+				return
+			}
+		}()
+	}
+
+	var o outcome
+	var total *logrender.Result
+	var runErr error
+	backoff := cfg.Worker.RetryBackoff.Duration
+	for attempt := 1; ; attempt++ {
+		var started bool
+		o, started, runErr = runAttempt(stateDir, w, cfg, agent, bin, dir, env, prompt, attempt, logFile, &current, stop)
+		total = addResult(total, o.Result)
+		if !started {
+			finish(stateDir, id, state.StatusError, cfg, o)
+			return runErr
+		}
+		if runErr == nil || attempt > w.Retries || timedOut.Load() || stopped(stop) {
+			break
+		}
+
+		// Nothing runs while we back off. A worker killed meanwhile, or
+		// while claude ran, stays killed.
+		if _, err := state.Transition(stateDir, id, []state.Status{state.StatusWorking}, func(w *state.Worker) error {
+			w.PID, w.PGID = 0, 0
+			return nil
+		}); err != nil {
+			break
+		}
+		state.AppendEvent(stateDir, id, state.Event{Type: state.EventRetry, ExitCode: o.ExitCode, Signal: o.Signal, Error: runErr.Error(), Detail: fmt.Sprintf("attempt %d/%d in %s", attempt+1, w.Retries+1, backoff)})
+		select {
+		case <-stop:
+		case <-time.After(backoff):
+		}
+		if stopped(stop) {
+			break
+		}
+		if cur, err := state.Read(stateDir, id); err != nil || cur.Status != state.StatusWorking {
+			break
+		}
+		fmt.Fprintf(logFile, "--- ccl: attempt %d of %d, resuming session ---\n", attempt+1, w.Retries+1)
+		backoff *= 2
+	}
+
+	// Decide now whether the worker timed out, not after the changes are
+	// captured below.
+	expired := !settled.CompareAndSwap(false, true)
+	if timer != nil {
+		timer.Stop()
+	}
+
+	// The agent has exited. Don't leave its PID behind while the changes
+	// are captured, or stale detection would take the worker for dead
+	// before finish records how it ended.
+	state.Transition(stateDir, id, []state.Status{state.StatusWorking, state.StatusPaused}, func(w *state.Worker) error {
+		w.PID, w.PGID = 0, 0
+		return nil
+	})
+
+	if gitInfo != nil {
+		captureEnd(dir, id, gitInfo)
+		o.Git = gitInfo
+	}
+	o.Result = total
+	if expired {
+		o.ErrorReason = fmt.Sprintf("timed out after %s", timeout)
+		finish(stateDir, id, state.StatusTimeout, cfg, o)
+	} else if runErr != nil {
+		o.ErrorReason = runErr.Error()
+		if strings.HasPrefix(o.ResultSubtype, "error") {
+			o.ErrorReason = o.ResultSubtype + " (" + o.ErrorReason + ")"
+		}
+		finish(stateDir, id, state.StatusError, cfg, o)
+	} else {
+		finish(stateDir, id, state.StatusDone, cfg, o)
+	}
+
+	// Opportunistic retention; a failure here must not fail the worker. The
+	// worker just finished stays for its result to be seen.
+	GC(stateDir, cfg, false, id)
+
+	return nil
+}
+
+// timeLeft is how much of timeout worker id has left, not counting time
+// spent paused since begin. While the worker is paused it is at least
+// pausedPoll, so that the timeout is checked again later rather than
+// busily.
+func timeLeft(stateDir, id string, begin time.Time, timeout time.Duration) time.Duration {
+	w, err := state.Read(stateDir, id)
+	if err != nil {
+		return 0
+	}
+	now := time.Now()
+	left := timeout - (now.Sub(begin) - w.PausedFor(now))
+	if w.Status == state.StatusPaused {
+		left = max(left, pausedPoll)
+	}
+	return left
+}
+
+// pausedPoll is how often the timeout is rechecked while a worker is paused.
+const pausedPoll = time.Second
+
+// proc is a running claude process group.
+type proc struct {
+	pgid   int
+	exited chan struct{} // closed once claude has been reaped
+}
+
+// runAttempt runs one attempt of w with runner r in dir and environment env,
+// appending its output to log, and returns how it exited. The first attempt
+// starts the session with prompt; later ones resume it, or start over if r
+// can't resume. started is false if the agent could not be started at all,
+// in which case err says why. Its process group is published in current
+// while it runs, and stopped straight away if stop is already closed. bin,
+// if set, replaces the runner's program.
+func runAttempt(stateDir string, w *state.Worker, cfg *config.Config, r Runner, bin, dir string, env []string, prompt string, attempt int, log *os.File, current *atomic.Pointer[proc], stop <-chan struct{}) (o outcome, started bool, err error) {
+	var argv []string
+	if attempt > 1 {
+		argv, err = r.ResumeCommand(w, retryPrompt)
+	}
+	if argv == nil && err == nil {
+		argv, err = r.Command(w, prompt)
+	}
+	if err != nil {
+		return outcome{ErrorReason: err.Error()}, false, err
+	}
+	if bin != "" {
+		argv[0] = bin
+	}
+
+	// Build and start command
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = dir
+	watcher := &resultWatcher{parseLine: logrender.Parser(r.LogFormat())}
+	cmd.Stdout = io.MultiWriter(log, watcher)
+	cmd.Stderr = log
+	cmd.Stdin = nil
+	cmd.Env = env
+	// claude leads its own process group so that it and any tools it
+	// started can be signalled together.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Don't wait forever on descendants still holding stdout after claude exits.
+	cmd.WaitDelay = cfg.Worker.KillGrace.Duration
+
 	if err := cmd.Start(); err != nil {
-		markError(stateDir, w, cfg)
-		return fmt.Errorf("start claude: %w", err)
+		err = fmt.Errorf("start %s: %w", r.Name(), err)
+		return outcome{ErrorReason: err.Error()}, false, err
 	}
 
 	// Update PID in state
-	w.PID = cmd.Process.Pid
-	state.Write(stateDir, w)
+	pid := cmd.Process.Pid
+	p := &proc{pgid: pid, exited: make(chan struct{})}
+	current.Store(p)
+	if stopped(stop) {
+		// Told to stop while claude was starting.
+		syscall.Kill(-pid, syscall.SIGTERM)
+	}
+	state.Transition(stateDir, w.ID, []state.Status{state.StatusWorking}, func(w *state.Worker) error {
+		w.PID = pid
+		w.PGID = pid
+		w.Attempt = attempt
+		return nil
+	})
+	state.AppendEvent(stateDir, w.ID, state.Event{Type: state.EventPID, PID: pid})
 
 	// Wait for completion
 	runErr := cmd.Wait()
-	signal.Stop(sigCh)
+	close(p.exited)
+	current.Store(nil)
 
-	now := time.Now()
-	w.FinishedAt = &now
+	if len(watcher.buf) > 0 {
+		// Keep whatever comes next in the log on its own line.
+		log.WriteString("\n")
+	}
+	watcher.flush()
+	o = exitOutcome(cmd.ProcessState)
+	o.ResultSubtype = watcher.subtype
+	o.Result = watcher.result
+	return o, true, runErr
+}
 
-	if runErr != nil {
-		markError(stateDir, w, cfg)
-	} else {
-		w.Status = state.StatusDone
-		state.Write(stateDir, w)
-		vars := hooks.Vars{ID: w.ID, Task: w.Task, Dir: w.Directory, Status: "done", SessionID: w.SessionID}
-		hooks.Fire(cfg.Hooks.OnDone, vars)
+// retryPrompt is the task given to claude when a failed attempt's session
+// is resumed.
+const retryPrompt = "Your previous run was interrupted before it finished. Continue the task where you left off."
+
+// addResult adds r's metrics to total, so that a retried worker reports
+// what all of its attempts cost.
+func addResult(total, r *logrender.Result) *logrender.Result {
+	if r == nil {
+		return total
+	}
+	if total == nil {
+		total = &logrender.Result{}
+	}
+	total.CostUSD += r.CostUSD
+	total.NumTurns += r.NumTurns
+	total.DurationMS += r.DurationMS
+	total.Usage.InputTokens += r.Usage.InputTokens
+	total.Usage.OutputTokens += r.Usage.OutputTokens
+	total.Usage.CacheCreationInputTokens += r.Usage.CacheCreationInputTokens
+	total.Usage.CacheReadInputTokens += r.Usage.CacheReadInputTokens
+	return total
+}
+
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// terminateGroup sends SIGTERM to process group pgid and SIGKILL once grace
+// has passed or its leader has exited, whichever is first, so that nothing
+// in the group outlives it.
+func terminateGroup(pgid int, grace time.Duration, exited <-chan struct{}) {
+	syscall.Kill(-pgid, syscall.SIGTERM)
+	select {
+	case <-exited:
+	case <-time.After(grace):
+	}
+	syscall.Kill(-pgid, syscall.SIGKILL)
+}
+
+// outcome describes how claude exited.
+type outcome struct {
+	ExitCode      *int
+	Signal        string
+	ErrorReason   string
+	ResultSubtype string
+	Result        *logrender.Result
+	Git           *state.GitInfo
+}
+
+// exitOutcome reads the exit code or terminating signal from ps.
+func exitOutcome(ps *os.ProcessState) outcome {
+	var o outcome
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		o.Signal = unix.SignalName(ws.Signal())
+		return o
+	}
+	code := ps.ExitCode()
+	o.ExitCode = &code
+	return o
+}
+
+// finish moves a working worker to its final status, records o, journals
+// the finished event and fires the matching hooks. A worker killed while
+// claude ran keeps its status but still gets o recorded; one that was
+// removed is left alone.
+func finish(stateDir, id string, status state.Status, cfg *config.Config, o outcome) {
+	var wasWorking bool
+	w, err := state.Transition(stateDir, id, []state.Status{state.StatusWorking, state.StatusPaused, state.StatusKilled}, func(w *state.Worker) error {
+		w.ExitCode = o.ExitCode
+		w.Signal = o.Signal
+		w.ErrorReason = o.ErrorReason
+		w.ResultSubtype = o.ResultSubtype
+		if o.Git != nil {
+			w.Git = o.Git
+		}
+		if r := o.Result; r != nil {
+			w.CostUSD = r.CostUSD
+			w.NumTurns = r.NumTurns
+			w.DurationMS = r.DurationMS
+			w.InputTokens = r.Usage.InputTokens
+			w.OutputTokens = r.Usage.OutputTokens
+			w.CacheCreationTokens = r.Usage.CacheCreationInputTokens
+			w.CacheReadTokens = r.Usage.CacheReadInputTokens
+		}
+		if w.Status != state.StatusWorking && w.Status != state.StatusPaused {
+			return nil
+		}
+		wasWorking = true
+		if w.StopRequestedAt != nil {
+			status = state.StatusKilled
+		}
+		now := time.Now()
+		w.PausedMS = w.PausedFor(now).Milliseconds()
+		w.PausedAt = nil
+		w.Status = status
+		w.FinishedAt = &now
+		return nil
+	})
+	if err != nil || !wasWorking {
+		return
+	}
+	e := state.Event{Type: state.EventFinished, Status: status, ExitCode: o.ExitCode, Signal: o.Signal, Error: o.ErrorReason}
+	if o.ResultSubtype != "" {
+		e.Detail = "result=" + o.ResultSubtype
+	}
+	state.AppendEvent(stateDir, id, e)
+
+	vars := HookVars(w)
+	switch status {
+	case state.StatusDone:
+		FireHooks(stateDir, "on_done", cfg.Hooks.OnDone, vars)
+	case state.StatusTimeout:
+		FireHooks(stateDir, "on_timeout", cfg.Hooks.OnTimeout, vars)
+	case state.StatusKilled:
+		// on_kill is fired by whoever stopped it.
+	default:
+		FireHooks(stateDir, "on_error", cfg.Hooks.OnError, vars)
 	}
+}
 
+// Signal sends sig to w's claude process group, or to claude alone for
+// workers started before process groups were recorded.
+func Signal(w *state.Worker, sig syscall.Signal) error {
+	switch {
+	case w.PGID > 0:
+		return syscall.Kill(-w.PGID, sig)
+	case w.PID > 0:
+		return syscall.Kill(w.PID, sig)
+	}
 	return nil
 }
 
-func markError(stateDir string, w *state.Worker, cfg *config.Config) {
-	now := time.Now()
-	w.Status = state.StatusError
-	w.FinishedAt = &now
-	state.Write(stateDir, w)
-	vars := hooks.Vars{ID: w.ID, Task: w.Task, Dir: w.Directory, Status: "error", SessionID: w.SessionID}
-	hooks.Fire(cfg.Hooks.OnError, vars)
+// isAlive reports whether process pid exists and hasn't exited. A zombie
+// still answers signal 0, so one that has exited but not been reaped by
+// its parent is checked for in /proc where available.
+func isAlive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	return !isZombie(pid)
+}
+
+func isZombie(pid int) bool {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// The state follows the command name, which may itself contain ")".
+	i := bytes.LastIndexByte(b, ')')
+	return i >= 0 && i+2 < len(b) && b[i+2] == 'Z'
 }
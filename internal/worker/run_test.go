@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/scottstav/wreccless/internal/config"
 	"github.com/scottstav/wreccless/internal/state"
@@ -48,6 +50,9 @@ func TestRunSuccess(t *testing.T) {
 	if updated.FinishedAt == nil {
 		t.Error("finished_at should be set")
 	}
+	if updated.PID != 0 {
+		t.Errorf("expected the exited agent's pid cleared, got %d", updated.PID)
+	}
 
 	logPath := filepath.Join(stateDir, "1000.log")
 	data, err := os.ReadFile(logPath)
@@ -79,3 +84,371 @@ func TestRunFailure(t *testing.T) {
 		t.Errorf("expected error, got %s", updated.Status)
 	}
 }
+
+func TestRunRejectsDoubleRun(t *testing.T) {
+	stateDir := t.TempDir()
+	binDir := t.TempDir()
+	mockClaude := writeMockClaude(t, binDir, 0)
+
+	// Our parent process stands in for a live runner that already claimed it.
+	w := &state.Worker{ID: "1002", Status: state.StatusWorking, Directory: t.TempDir(), Task: "dup", SessionID: "s", RunnerPID: os.Getppid()}
+	state.Write(stateDir, w)
+
+	if err := Run(stateDir, "1002", config.Defaults(), mockClaude); err == nil {
+		t.Fatal("expected second run to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "1002.log")); !os.IsNotExist(err) {
+		t.Error("rejected run should not start claude")
+	}
+}
+
+func TestRunRequiresWorking(t *testing.T) {
+	stateDir := t.TempDir()
+	w := &state.Worker{ID: "1003", Status: state.StatusPending, Directory: t.TempDir(), Task: "p", SessionID: "s"}
+	state.Write(stateDir, w)
+
+	if err := Run(stateDir, "1003", config.Defaults(), "/bin/true"); err == nil {
+		t.Fatal("expected run of a pending worker to be rejected")
+	}
+}
+
+func TestRunJournal(t *testing.T) {
+	stateDir := t.TempDir()
+	binDir := t.TempDir()
+	mockClaude := writeMockClaude(t, binDir, 1)
+
+	w := &state.Worker{ID: "1004", Status: state.StatusWorking, Directory: t.TempDir(), Task: "journal", SessionID: "s"}
+	state.Write(stateDir, w)
+	Run(stateDir, "1004", config.Defaults(), mockClaude)
+
+	events, _ := state.ReadEvents(stateDir, "1004")
+	var types []state.EventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	want := []state.EventType{state.EventStarted, state.EventPID, state.EventFinished}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Fatalf("events: got %v, want %v", types, want)
+	}
+	last := events[len(events)-1]
+	if last.Status != state.StatusError || last.ExitCode == nil || *last.ExitCode != 1 {
+		t.Errorf("finished event: %+v", last)
+	}
+}
+
+func TestRunDoesNotOverwriteKilled(t *testing.T) {
+	stateDir := t.TempDir()
+	binDir := t.TempDir()
+	script := filepath.Join(binDir, "slow-claude")
+	os.WriteFile(script, []byte("#!/bin/sh\nsleep 0.5\nexit 1\n"), 0755)
+
+	w := &state.Worker{ID: "1005", Status: state.StatusWorking, Directory: t.TempDir(), Task: "slow", SessionID: "s"}
+	state.Write(stateDir, w)
+
+	done := make(chan error)
+	go func() { done <- Run(stateDir, "1005", config.Defaults(), script) }()
+	time.Sleep(200 * time.Millisecond)
+	state.Transition(stateDir, "1005", []state.Status{state.StatusWorking}, func(w *state.Worker) error {
+		w.Status = state.StatusKilled
+		return nil
+	})
+	<-done
+
+	updated, _ := state.Read(stateDir, "1005")
+	if updated.Status != state.StatusKilled {
+		t.Errorf("killed worker should stay killed, got %s", updated.Status)
+	}
+}
+
+func TestRunRecordsOutcome(t *testing.T) {
+	stateDir := t.TempDir()
+	binDir := t.TempDir()
+	script := filepath.Join(binDir, "max-turns-claude")
+	os.WriteFile(script, []byte(`#!/bin/sh
+echo '{"type":"result","subtype":"error_max_turns","is_error":true}'
+exit 2
+`), 0755)
+
+	w := &state.Worker{ID: "1006", Status: state.StatusWorking, Directory: t.TempDir(), Task: "loop", SessionID: "s"}
+	state.Write(stateDir, w)
+	Run(stateDir, "1006", config.Defaults(), script)
+
+	updated, _ := state.Read(stateDir, "1006")
+	if updated.ExitCode == nil || *updated.ExitCode != 2 {
+		t.Errorf("exit code: %v", updated.ExitCode)
+	}
+	if updated.ResultSubtype != "error_max_turns" {
+		t.Errorf("result subtype: %q", updated.ResultSubtype)
+	}
+	if updated.ErrorReason != "error_max_turns (exit status 2)" {
+		t.Errorf("error reason: %q", updated.ErrorReason)
+	}
+}
+
+func TestRunRecordsSignal(t *testing.T) {
+	stateDir := t.TempDir()
+	binDir := t.TempDir()
+	script := filepath.Join(binDir, "doomed-claude")
+	os.WriteFile(script, []byte("#!/bin/sh\nkill -KILL $$\n"), 0755)
+
+	w := &state.Worker{ID: "1007", Status: state.StatusWorking, Directory: t.TempDir(), Task: "oom", SessionID: "s"}
+	state.Write(stateDir, w)
+	Run(stateDir, "1007", config.Defaults(), script)
+
+	updated, _ := state.Read(stateDir, "1007")
+	if updated.Status != state.StatusError || updated.Signal != "SIGKILL" || updated.ExitCode != nil {
+		t.Errorf("expected error by SIGKILL, got %+v", updated)
+	}
+}
+
+func TestRunRecordsStartFailure(t *testing.T) {
+	stateDir := t.TempDir()
+	w := &state.Worker{ID: "1008", Status: state.StatusWorking, Directory: t.TempDir(), Task: "missing", SessionID: "s"}
+	state.Write(stateDir, w)
+	Run(stateDir, "1008", config.Defaults(), filepath.Join(t.TempDir(), "no-such-claude"))
+
+	updated, _ := state.Read(stateDir, "1008")
+	if updated.Status != state.StatusError || !strings.Contains(updated.ErrorReason, "start claude") {
+		t.Errorf("expected start failure reason, got %+v", updated)
+	}
+}
+
+func TestRunRecordsLogFailure(t *testing.T) {
+	stateDir := t.TempDir()
+	w := &state.Worker{ID: "1015", Status: state.StatusWorking, Directory: t.TempDir(), Task: "no log", SessionID: "s"}
+	state.Write(stateDir, w)
+	// A directory where the log goes can't be created as a file.
+	os.Mkdir(filepath.Join(stateDir, "1015.log"), 0755)
+	if err := Run(stateDir, "1015", config.Defaults(), "/bin/true"); err == nil {
+		t.Error("expected an error creating the log")
+	}
+
+	updated, _ := state.Read(stateDir, "1015")
+	if updated.Status != state.StatusError || !strings.Contains(updated.ErrorReason, "create log") {
+		t.Errorf("expected log failure reason, got %+v", updated)
+	}
+}
+
+func TestRunKeepsOwnWorkerFromGC(t *testing.T) {
+	stateDir := t.TempDir()
+	mockClaude := writeMockClaude(t, t.TempDir(), 0)
+	state.Write(stateDir, &state.Worker{ID: "1016", Status: state.StatusWorking, Directory: t.TempDir(), Task: "t", SessionID: "s"})
+	cfg := config.Defaults()
+	// Its log alone is over the budget.
+	cfg.Retention.MaxLogBytes = 1
+	if err := Run(stateDir, "1016", cfg, mockClaude); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	w, err := state.Read(stateDir, "1016")
+	if err != nil || w.Status != state.StatusDone {
+		t.Fatalf("expected the finished worker kept, got %+v, %v", w, err)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "1016.log")); err != nil {
+		t.Errorf("expected its log kept: %v", err)
+	}
+}
+
+func TestHookVars(t *testing.T) {
+	code := 3
+	vars := HookVars(&state.Worker{ID: "1", Status: state.StatusError, ExitCode: &code, ResultSubtype: "error_during_execution"})
+	if vars.ExitCode != "3" || vars.ResultSubtype != "error_during_execution" || vars.Status != "error" {
+		t.Errorf("unexpected vars: %+v", vars)
+	}
+}
+
+func TestRunRecordsMetrics(t *testing.T) {
+	stateDir := t.TempDir()
+	binDir := t.TempDir()
+	script := filepath.Join(binDir, "metered-claude")
+	os.WriteFile(script, []byte(`#!/bin/sh
+echo '{"type":"assistant","content":"done"}'
+printf '%s' '{"type":"result","subtype":"success","total_cost_usd":0.5,"num_turns":4,"duration_ms":1500,"usage":{"input_tokens":10,"output_tokens":20,"cache_creation_input_tokens":30,"cache_read_input_tokens":40}}'
+`), 0755)
+
+	w := &state.Worker{ID: "1009", Status: state.StatusWorking, Directory: t.TempDir(), Task: "meter", SessionID: "s"}
+	state.Write(stateDir, w)
+	Run(stateDir, "1009", config.Defaults(), script)
+
+	u, _ := state.Read(stateDir, "1009")
+	if u.CostUSD != 0.5 || u.NumTurns != 4 || u.DurationMS != 1500 {
+		t.Errorf("metrics not recorded: %+v", u)
+	}
+	if u.InputTokens != 10 || u.OutputTokens != 20 || u.CacheCreationTokens != 30 || u.CacheReadTokens != 40 {
+		t.Errorf("usage not recorded: %+v", u)
+	}
+	if u.ResultSubtype != "success" {
+		t.Errorf("result subtype: %q", u.ResultSubtype)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	stateDir := t.TempDir()
+	binDir := t.TempDir()
+	script := filepath.Join(binDir, "slow-claude")
+	os.WriteFile(script, []byte("#!/bin/sh\nsleep 30\n"), 0755)
+
+	w := &state.Worker{ID: "1010", Status: state.StatusWorking, Directory: t.TempDir(), Task: "loop", SessionID: "s", TimeoutMS: 200}
+	state.Write(stateDir, w)
+	start := time.Now()
+	Run(stateDir, "1010", config.Defaults(), script)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timed out worker took %s to stop", elapsed)
+	}
+
+	updated, _ := state.Read(stateDir, "1010")
+	if updated.Status != state.StatusTimeout || updated.Signal != "SIGTERM" || updated.PGID != updated.PID {
+		t.Errorf("expected timeout by SIGTERM, got %+v", updated)
+	}
+	if updated.ErrorReason != "timed out after 200ms" {
+		t.Errorf("error reason = %q", updated.ErrorReason)
+	}
+	events, _ := state.ReadEvents(stateDir, "1010")
+	var sawTimeout bool
+	for _, e := range events {
+		sawTimeout = sawTimeout || e.Type == state.EventTimeout
+	}
+	if !sawTimeout {
+		t.Errorf("expected a timeout event, got %+v", events)
+	}
+}
+
+func TestRunTimeoutEscalatesToKill(t *testing.T) {
+	stateDir := t.TempDir()
+	binDir := t.TempDir()
+	script := filepath.Join(binDir, "stubborn-claude")
+	os.WriteFile(script, []byte("#!/bin/sh\ntrap '' TERM\nsleep 30\n"), 0755)
+
+	w := &state.Worker{ID: "1011", Status: state.StatusWorking, Directory: t.TempDir(), Task: "loop", SessionID: "s", TimeoutMS: 100}
+	state.Write(stateDir, w)
+	cfg := config.Defaults()
+	cfg.Worker.KillGrace = config.Duration{Duration: 200 * time.Millisecond}
+	start := time.Now()
+	Run(stateDir, "1011", cfg, script)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stubborn worker took %s to stop", elapsed)
+	}
+
+	updated, _ := state.Read(stateDir, "1011")
+	if updated.Status != state.StatusTimeout || updated.Signal != "SIGKILL" {
+		t.Errorf("expected timeout by SIGKILL, got %+v", updated)
+	}
+}
+
+// writeFlakyClaude writes a mock claude that fails unless resuming, and
+// reports a cost on every attempt.
+func writeFlakyClaude(t *testing.T, dir string) string {
+	t.Helper()
+	script := filepath.Join(dir, "flaky-claude")
+	os.WriteFile(script, []byte(`#!/bin/sh
+echo '{"type":"result","subtype":"success","total_cost_usd":0.5,"num_turns":2}'
+for arg; do
+	[ "$arg" = "--resume" ] && exit 0
+done
+exit 1
+`), 0755)
+	return script
+}
+
+func TestRunRetriesWithResume(t *testing.T) {
+	stateDir := t.TempDir()
+	script := writeFlakyClaude(t, t.TempDir())
+
+	w := &state.Worker{ID: "1012", Status: state.StatusWorking, Directory: t.TempDir(), Task: "flaky", SessionID: "s", Retries: 2}
+	state.Write(stateDir, w)
+	cfg := config.Defaults()
+	cfg.Worker.RetryBackoff = config.Duration{Duration: time.Millisecond}
+	if err := Run(stateDir, "1012", cfg, script); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	updated, _ := state.Read(stateDir, "1012")
+	if updated.Status != state.StatusDone || updated.Attempt != 2 {
+		t.Errorf("expected done on attempt 2, got %s on %d", updated.Status, updated.Attempt)
+	}
+	if updated.CostUSD != 1 || updated.NumTurns != 4 {
+		t.Errorf("expected metrics summed over attempts, got cost %v turns %d", updated.CostUSD, updated.NumTurns)
+	}
+	log, _ := os.ReadFile(filepath.Join(stateDir, "1012.log"))
+	if !strings.Contains(string(log), "--- ccl: attempt 2 of 3, resuming session ---\n") {
+		t.Errorf("expected attempt separator in log:\n%s", log)
+	}
+	events, _ := state.ReadEvents(stateDir, "1012")
+	var retries int
+	for _, e := range events {
+		if e.Type == state.EventRetry {
+			retries++
+		}
+	}
+	if retries != 1 {
+		t.Errorf("expected 1 retry event, got %d", retries)
+	}
+}
+
+func TestRunRetriesExhausted(t *testing.T) {
+	stateDir := t.TempDir()
+	binDir := t.TempDir()
+	mockClaude := writeMockClaude(t, binDir, 1)
+
+	w := &state.Worker{ID: "1013", Status: state.StatusWorking, Directory: t.TempDir(), Task: "broken", SessionID: "s", Retries: 2}
+	state.Write(stateDir, w)
+	cfg := config.Defaults()
+	cfg.Worker.RetryBackoff = config.Duration{Duration: time.Millisecond}
+	cfg.Hooks.OnError = []string{"true"}
+	Run(stateDir, "1013", cfg, mockClaude)
+
+	updated, _ := state.Read(stateDir, "1013")
+	if updated.Status != state.StatusError || updated.Attempt != 3 {
+		t.Errorf("expected error after 3 attempts, got %s on %d", updated.Status, updated.Attempt)
+	}
+	events, _ := state.ReadEvents(stateDir, "1013")
+	var onError int
+	for _, e := range events {
+		if e.Type == state.EventHook && e.Hook == "on_error" {
+			onError++
+		}
+	}
+	if onError != 1 {
+		t.Errorf("expected on_error to fire once, got %d", onError)
+	}
+}
+
+func TestRunDoesNotRetryKilled(t *testing.T) {
+	stateDir := t.TempDir()
+	binDir := t.TempDir()
+	script := filepath.Join(binDir, "killed-claude")
+	// Claude waits for the test to kill the worker, then fails.
+	os.WriteFile(script, []byte(fmt.Sprintf(`#!/bin/sh
+while [ ! -e %s/go ]; do sleep 0.01; done
+exit 1
+`, binDir)), 0755)
+
+	w := &state.Worker{ID: "1014", Status: state.StatusWorking, Directory: t.TempDir(), Task: "t", SessionID: "s", Retries: 2}
+	state.Write(stateDir, w)
+	cfg := config.Defaults()
+	cfg.Worker.RetryBackoff = config.Duration{Duration: time.Millisecond}
+	done := make(chan error)
+	go func() { done <- Run(stateDir, "1014", cfg, script) }()
+
+	// Simulate "ccl kill" landing while claude runs, once the runner has
+	// recorded its pid.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if w, _ := state.Read(stateDir, "1014"); w != nil && w.PID > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("claude's pid was never recorded")
+		}
+	}
+	state.Transition(stateDir, "1014", []state.Status{state.StatusWorking}, func(w *state.Worker) error {
+		w.Status = state.StatusKilled
+		return nil
+	})
+	os.WriteFile(filepath.Join(binDir, "go"), nil, 0644)
+	<-done
+
+	updated, _ := state.Read(stateDir, "1014")
+	if updated.Status != state.StatusKilled || updated.Attempt != 1 {
+		t.Errorf("expected killed worker not to be retried, got %s on attempt %d", updated.Status, updated.Attempt)
+	}
+}
@@ -0,0 +1,154 @@
+package worker
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/logrender"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// DefaultRunner is the runner used by workers that don't name one.
+const DefaultRunner = "claude"
+
+// Runner is an agent CLI that a worker's task is dispatched to. Commands
+// are returned as argv, program first, and run in the worker's directory.
+type Runner interface {
+	Name() string
+	// Command starts a new session on task.
+	Command(w *state.Worker, task string) ([]string, error)
+	// ResumeCommand continues w's session after a failed attempt with
+	// prompt. It returns nil if the runner can't resume, and the task is
+	// started over instead.
+	ResumeCommand(w *state.Worker, prompt string) ([]string, error)
+	// InteractiveCommand opens w's session for the user, for "ccl resume".
+	InteractiveCommand(w *state.Worker) ([]string, error)
+	// LogFormat is the logrender format of the runner's output.
+	LogFormat() string
+}
+
+// RunnerFor returns the runner called name: claude for "" or "claude",
+// otherwise the matching [runners.<name>] from cfg.
+func RunnerFor(cfg *config.Config, name string) (Runner, error) {
+	if name == "" || name == DefaultRunner {
+		return claudeRunner{cfg.Claude}, nil
+	}
+	rc, ok := cfg.Runners[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown runner %q", name)
+	}
+	if len(rc.Command) == 0 {
+		return nil, fmt.Errorf("runner %q has no command", name)
+	}
+	if !logrender.ValidFormat(rc.Format) {
+		return nil, fmt.Errorf("runner %q: unknown format %q", name, rc.Format)
+	}
+	return templateRunner{name: name, cfg: rc}, nil
+}
+
+// claudeRunner runs claude -p with the flags ccl depends on. ccl picks the
+// session ID so it is known before claude starts.
+type claudeRunner struct {
+	cfg config.ClaudeConfig
+}
+
+func (claudeRunner) Name() string { return DefaultRunner }
+
+func (r claudeRunner) Command(w *state.Worker, task string) ([]string, error) {
+	return r.print(w, "--session-id", task), nil
+}
+
+func (r claudeRunner) ResumeCommand(w *state.Worker, prompt string) ([]string, error) {
+	return r.print(w, "--resume", prompt), nil
+}
+
+func (r claudeRunner) InteractiveCommand(w *state.Worker) ([]string, error) {
+	args := []string{"claude", "--resume", w.SessionID}
+	if r.cfg.SkipPermissions {
+		args = append(args, "--dangerously-skip-permissions")
+	}
+	return args, nil
+}
+
+func (claudeRunner) LogFormat() string { return logrender.FormatStreamJSON }
+
+// print builds a non-interactive claude command line that starts or resumes
+// (sessionFlag) w's session with prompt.
+func (r claudeRunner) print(w *state.Worker, sessionFlag, prompt string) []string {
+	args := []string{
+		"claude",
+		"-p",
+		"--output-format", "stream-json",
+		"--verbose",
+		sessionFlag, w.SessionID,
+	}
+	if r.cfg.SkipPermissions {
+		args = append(args, "--dangerously-skip-permissions")
+	}
+	if r.cfg.SystemPrompt != "" {
+		args = append(args, "--append-system-prompt", r.cfg.SystemPrompt)
+	}
+	args = append(args, r.cfg.ExtraFlags...)
+	return append(args, prompt)
+}
+
+// templateRunner runs a command line configured in [runners.<name>].
+type templateRunner struct {
+	name string
+	cfg  config.RunnerConfig
+}
+
+// templateVars are what runner command templates can refer to.
+type templateVars struct {
+	ID        string
+	Task      string
+	Dir       string
+	SessionID string
+	Prompt    string
+}
+
+func (r templateRunner) Name() string { return r.name }
+
+func (r templateRunner) Command(w *state.Worker, task string) ([]string, error) {
+	return r.expand(r.cfg.Command, w, task, "")
+}
+
+func (r templateRunner) ResumeCommand(w *state.Worker, prompt string) ([]string, error) {
+	if len(r.cfg.Resume) == 0 {
+		return nil, nil
+	}
+	return r.expand(r.cfg.Resume, w, w.Task, prompt)
+}
+
+func (r templateRunner) InteractiveCommand(w *state.Worker) ([]string, error) {
+	if len(r.cfg.Interactive) == 0 {
+		return nil, fmt.Errorf("runner %q has no interactive command", r.name)
+	}
+	return r.expand(r.cfg.Interactive, w, w.Task, "")
+}
+
+func (r templateRunner) LogFormat() string {
+	if r.cfg.Format == "" {
+		return logrender.FormatPlain
+	}
+	return r.cfg.Format
+}
+
+func (r templateRunner) expand(tmpls []string, w *state.Worker, task, prompt string) ([]string, error) {
+	vars := templateVars{ID: w.ID, Task: task, Dir: WorkDir(w), SessionID: w.SessionID, Prompt: prompt}
+	args := make([]string, len(tmpls))
+	for i, s := range tmpls {
+		t, err := template.New(r.name).Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("runner %q: %w", r.name, err)
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, vars); err != nil {
+			return nil, fmt.Errorf("runner %q: %w", r.name, err)
+		}
+		args[i] = buf.String()
+	}
+	return args, nil
+}
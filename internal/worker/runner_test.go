@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/logrender"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestRunnerForClaude(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Claude.ExtraFlags = []string{"--model", "opus"}
+	r, err := RunnerFor(cfg, "")
+	if err != nil {
+		t.Fatalf("RunnerFor: %v", err)
+	}
+	w := &state.Worker{SessionID: "abc"}
+	start, _ := r.Command(w, "fix it")
+	got := strings.Join(start, " ")
+	if !strings.HasPrefix(got, "claude -p --output-format stream-json --verbose --session-id abc") || !strings.HasSuffix(got, "--model opus fix it") {
+		t.Errorf("unexpected start command: %s", got)
+	}
+	resume, _ := r.ResumeCommand(w, "go on")
+	if got := strings.Join(resume, " "); !strings.Contains(got, "--resume abc") || !strings.HasSuffix(got, "go on") {
+		t.Errorf("unexpected resume command: %s", got)
+	}
+	if r.LogFormat() != logrender.FormatStreamJSON {
+		t.Errorf("claude logs stream-json, got %q", r.LogFormat())
+	}
+}
+
+func TestRunnerForTemplate(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Runners = map[string]config.RunnerConfig{
+		"aider": {Command: []string{"aider", "--message", "{{.Task}}", "--dir={{.Dir}}"}},
+		"empty": {},
+		"odd":   {Command: []string{"x"}, Format: "xml"},
+	}
+	r, err := RunnerFor(cfg, "aider")
+	if err != nil {
+		t.Fatalf("RunnerFor: %v", err)
+	}
+	w := &state.Worker{ID: "1", Directory: "/src", Task: "fix it"}
+	argv, err := r.Command(w, w.Task)
+	if err != nil || fmt.Sprint(argv) != "[aider --message fix it --dir=/src]" {
+		t.Errorf("unexpected command %q (%v)", argv, err)
+	}
+	if argv, _ := r.ResumeCommand(w, "go on"); argv != nil {
+		t.Errorf("runner without resume should start over, got %q", argv)
+	}
+	if _, err := r.InteractiveCommand(w); err == nil {
+		t.Error("expected an error without an interactive command")
+	}
+	if r.LogFormat() != logrender.FormatPlain {
+		t.Errorf("expected plain output by default, got %q", r.LogFormat())
+	}
+
+	for _, name := range []string{"missing", "empty", "odd"} {
+		if _, err := RunnerFor(cfg, name); err == nil {
+			t.Errorf("expected an error for runner %q", name)
+		}
+	}
+}
+
+func TestRunTemplateRunner(t *testing.T) {
+	stateDir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.Runners = map[string]config.RunnerConfig{
+		"echo": {Command: []string{"sh", "-c", `echo "working on: $1"`, "sh", "{{.Task}}"}},
+	}
+	w := &state.Worker{ID: "1020", Status: state.StatusWorking, Directory: t.TempDir(), Task: "the task", SessionID: "s", Runner: "echo"}
+	state.Write(stateDir, w)
+	if err := Run(stateDir, "1020", cfg, ""); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	updated, _ := state.Read(stateDir, "1020")
+	if updated.Status != state.StatusDone || updated.LogFormat != logrender.FormatPlain {
+		t.Errorf("expected done with a plain log, got %+v", updated)
+	}
+	log, _ := os.ReadFile(filepath.Join(stateDir, "1020.log"))
+	if string(log) != "working on: the task\n" {
+		t.Errorf("unexpected log %q", log)
+	}
+}
+
+func TestRunUnknownRunner(t *testing.T) {
+	stateDir := t.TempDir()
+	w := &state.Worker{ID: "1021", Status: state.StatusWorking, Directory: t.TempDir(), Task: "t", SessionID: "s", Runner: "nope"}
+	state.Write(stateDir, w)
+	if err := Run(stateDir, "1021", config.Defaults(), ""); err == nil {
+		t.Error("expected an error for an unknown runner")
+	}
+	updated, _ := state.Read(stateDir, "1021")
+	if updated.Status != state.StatusError || !strings.Contains(updated.ErrorReason, `unknown runner "nope"`) {
+		t.Errorf("expected the runner error recorded, got %+v", updated)
+	}
+}
+
+func TestRunProfileEnv(t *testing.T) {
+	stateDir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.Runners = map[string]config.RunnerConfig{
+		"env": {Command: []string{"sh", "-c", `echo "$GREETING $TARGET"`}},
+	}
+	cfg.Claude.Env = map[string]string{"GREETING": "hello", "TARGET": "base"}
+	cfg.Profiles = map[string]config.ProfileConfig{
+		"p": {Env: map[string]string{"TARGET": "profile"}},
+	}
+	w := &state.Worker{ID: "1022", Status: state.StatusWorking, Directory: t.TempDir(), Task: "t", SessionID: "s", Runner: "env", Profile: "p"}
+	state.Write(stateDir, w)
+	if err := Run(stateDir, "1022", cfg, ""); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	log, _ := os.ReadFile(filepath.Join(stateDir, "1022.log"))
+	if string(log) != "hello profile\n" {
+		t.Errorf("expected the profile's env merged over [claude.env], got %q", log)
+	}
+
+	w = &state.Worker{ID: "1023", Status: state.StatusWorking, Directory: t.TempDir(), Task: "t", SessionID: "s", Profile: "gone"}
+	state.Write(stateDir, w)
+	if err := Run(stateDir, "1023", cfg, ""); err == nil {
+		t.Error("expected an error for a profile no longer in config")
+	}
+}
+
+func TestRunProjectConfig(t *testing.T) {
+	stateDir := t.TempDir()
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, config.ProjectFile), []byte("[claude]\nsystem_prompt = \"run make test first\"\n"), 0644)
+	script := filepath.Join(t.TempDir(), "mock-claude")
+	os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\"\n"), 0755)
+	w := &state.Worker{ID: "1024", Status: state.StatusWorking, Directory: project, Task: "t", SessionID: "s"}
+	state.Write(stateDir, w)
+	if err := Run(stateDir, "1024", config.Defaults(), script); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	log, _ := os.ReadFile(filepath.Join(stateDir, "1024.log"))
+	if !strings.Contains(string(log), "--append-system-prompt run make test first") {
+		t.Errorf("expected the project's .ccl.toml to apply, got %q", log)
+	}
+
+	// Commands and environment come from the user config only.
+	os.WriteFile(filepath.Join(project, config.ProjectFile), []byte("[runners.env]\ncommand = [\"sh\", \"-c\", \"echo $WHERE\"]\n[claude.env]\nWHERE = \"project\"\n"), 0644)
+	w = &state.Worker{ID: "1025", Status: state.StatusWorking, Directory: project, Task: "t", SessionID: "s", Runner: "env"}
+	state.Write(stateDir, w)
+	if err := Run(stateDir, "1025", config.Defaults(), ""); err == nil || !strings.Contains(err.Error(), "unknown runner") {
+		t.Errorf("expected the project's runner to be ignored, got %v", err)
+	}
+}
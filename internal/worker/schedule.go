@@ -0,0 +1,167 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// Launcher starts the runner for worker id.
+type Launcher func(id string) error
+
+// SpawnLauncher returns a Launcher that starts workers with SpawnRun.
+func SpawnLauncher(cclBin, configPath, stateDir string) Launcher {
+	return func(id string) error {
+		return SpawnRun(id, cclBin, configPath, stateDir)
+	}
+}
+
+// Promote first settles blocked workers, queueing those whose dependencies
+// are met and killing those whose never will be, then starts queued workers
+// while the [worker] concurrency limits allow, highest priority first and
+// FIFO within a priority. Decisions are made under the scheduler lock so
+// concurrent callers never overfill the slots. on_kill hooks fire for each
+// killed worker and on_start hooks for each started one, which are returned.
+func Promote(stateDir string, cfg *config.Config, launch Launcher) ([]*state.Worker, error) {
+	unlock, err := state.LockScheduler(stateDir)
+	if err != nil {
+		return nil, fmt.Errorf("lock scheduler: %w", err)
+	}
+	killed := unblockLocked(stateDir)
+	started, err := promoteLocked(stateDir, cfg.Worker, launch)
+	unlock()
+
+	for _, w := range killed {
+		FireHooks(stateDir, "on_kill", ConfigFor(cfg, w).Hooks.OnKill, HookVars(w))
+	}
+	for _, w := range started {
+		FireHooks(stateDir, "on_start", ConfigFor(cfg, w).Hooks.OnStart, HookVars(w))
+	}
+	return started, err
+}
+
+// Schedule runs Promote, starting workers with this executable as "ccl run",
+// and returns worker id as it stands afterwards: working if it got a slot,
+// still queued or blocked if not. A worker that failed to start is returned
+// with its error reason as the error.
+func Schedule(stateDir, configPath string, cfg *config.Config, id string) (*state.Worker, error) {
+	cclBin, _ := os.Executable()
+	if _, err := Promote(stateDir, cfg, SpawnLauncher(cclBin, configPath, stateDir)); err != nil {
+		return nil, err
+	}
+	w, err := state.Read(stateDir, id)
+	if err != nil {
+		return nil, err
+	}
+	if w.Status == state.StatusError && w.ErrorReason != "" {
+		return w, errors.New(w.ErrorReason)
+	}
+	return w, nil
+}
+
+func promoteLocked(stateDir string, limits config.WorkerConfig, launch Launcher) ([]*state.Worker, error) {
+	workers, err := state.List(stateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	running := 0
+	perDir := map[string]int{}
+	var queued []*state.Worker
+	for _, w := range workers {
+		switch {
+		case (w.Status == state.StatusWorking || w.Status == state.StatusPaused) && holdsSlot(w):
+			running++
+			perDir[w.Directory]++
+		case w.Status == state.StatusQueued:
+			queued = append(queued, w)
+		}
+	}
+	sort.SliceStable(queued, func(i, j int) bool {
+		a, b := queued[i], queued[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return queuedTime(a).Before(queuedTime(b))
+	})
+
+	var started []*state.Worker
+	for _, q := range queued {
+		if limits.MaxConcurrent > 0 && running >= limits.MaxConcurrent {
+			break
+		}
+		if limits.MaxConcurrentPerDir > 0 && perDir[q.Directory] >= limits.MaxConcurrentPerDir {
+			continue
+		}
+		w, err := state.Transition(stateDir, q.ID, []state.Status{state.StatusQueued}, func(w *state.Worker) error {
+			now := time.Now()
+			w.Status = state.StatusWorking
+			w.StartedAt = &now
+			if w.SessionID == "" {
+				w.SessionID = uuid.New().String()
+			}
+			return nil
+		})
+		if err != nil {
+			continue
+		}
+		detail := fmt.Sprintf("%d running", running+1)
+		if limits.MaxConcurrent > 0 {
+			detail = fmt.Sprintf("slot %d/%d", running+1, limits.MaxConcurrent)
+		}
+		state.AppendEvent(stateDir, w.ID, state.Event{Type: state.EventScheduled, Detail: detail})
+
+		if err := launch(w.ID); err != nil {
+			reason := "spawn worker: " + err.Error()
+			state.Transition(stateDir, w.ID, []state.Status{state.StatusWorking}, func(w *state.Worker) error {
+				now := time.Now()
+				w.Status = state.StatusError
+				w.FinishedAt = &now
+				w.ErrorReason = reason
+				return nil
+			})
+			state.AppendEvent(stateDir, w.ID, state.Event{Type: state.EventFinished, Status: state.StatusError, Error: reason})
+			continue
+		}
+		running++
+		perDir[w.Directory]++
+		started = append(started, w)
+	}
+	return started, nil
+}
+
+// holdsSlot reports whether a working or paused worker still counts against
+// the concurrency limits. One whose runner has died, or never claimed it,
+// no longer does, even before stale detection marks it.
+func holdsSlot(w *state.Worker) bool {
+	if w.RunnerPID == 0 {
+		return !unclaimed(w)
+	}
+	return isAlive(w.RunnerPID)
+}
+
+// claimWindow is how long a started worker's runner has to record its pid
+// before the worker is taken for dead.
+const claimWindow = time.Minute
+
+// unclaimed reports whether w was started more than claimWindow ago and no
+// runner or agent pid has been recorded for it since.
+func unclaimed(w *state.Worker) bool {
+	return w.RunnerPID <= 0 && w.PID <= 0 && w.StartedAt != nil && time.Since(*w.StartedAt) > claimWindow
+}
+
+func queuedTime(w *state.Worker) time.Time {
+	if w.QueuedAt != nil {
+		return *w.QueuedAt
+	}
+	if w.CreatedAt != nil {
+		return *w.CreatedAt
+	}
+	return time.Time{}
+}
@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func recordLaunches(ids *[]string) Launcher {
+	return func(id string) error {
+		*ids = append(*ids, id)
+		return nil
+	}
+}
+
+func queue(t *testing.T, dir, id, wdir string, priority int, queuedAt time.Time) {
+	t.Helper()
+	w := &state.Worker{ID: id, Status: state.StatusQueued, Directory: wdir, Task: id, SessionID: "s", Priority: priority, QueuedAt: &queuedAt}
+	if err := state.Write(dir, w); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestPromoteRespectsLimitAndOrder(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	// A live runner (our parent) holds one of the two slots.
+	state.Write(dir, &state.Worker{ID: "2000", Status: state.StatusWorking, Directory: "/a", RunnerPID: os.Getppid()})
+	queue(t, dir, "2001", "/a", 0, now)
+	queue(t, dir, "2002", "/a", 0, now.Add(time.Second))
+	queue(t, dir, "2003", "/b", 5, now.Add(2*time.Second))
+
+	cfg := config.Defaults()
+	cfg.Worker.MaxConcurrent = 2
+	var launched []string
+	started, err := Promote(dir, cfg, recordLaunches(&launched))
+	if err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+	if fmt.Sprint(launched) != "[2003]" || len(started) != 1 {
+		t.Fatalf("expected only the high-priority worker to start, got %v", launched)
+	}
+	w, _ := state.Read(dir, "2003")
+	if w.Status != state.StatusWorking || w.StartedAt == nil {
+		t.Errorf("promoted worker: %+v", w)
+	}
+
+	// The first worker finishes; FIFO picks the oldest queued next.
+	state.Transition(dir, "2000", nil, func(w *state.Worker) error { w.Status = state.StatusDone; return nil })
+	launched = nil
+	Promote(dir, cfg, recordLaunches(&launched))
+	if fmt.Sprint(launched) != "[2001]" {
+		t.Errorf("expected FIFO promotion of 2001, got %v", launched)
+	}
+	if w, _ := state.Read(dir, "2002"); w.Status != state.StatusQueued {
+		t.Errorf("2002 should still be queued, got %s", w.Status)
+	}
+}
+
+func TestPromotePerDirLimit(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	queue(t, dir, "2010", "/a", 0, now)
+	queue(t, dir, "2011", "/a", 0, now.Add(time.Second))
+	queue(t, dir, "2012", "/b", 0, now.Add(2*time.Second))
+
+	cfg := config.Defaults()
+	cfg.Worker.MaxConcurrentPerDir = 1
+	var launched []string
+	Promote(dir, cfg, recordLaunches(&launched))
+	if fmt.Sprint(launched) != "[2010 2012]" {
+		t.Errorf("expected one worker per directory, got %v", launched)
+	}
+}
+
+func TestPromoteIgnoresDeadRunners(t *testing.T) {
+	dir := t.TempDir()
+	state.Write(dir, &state.Worker{ID: "2020", Status: state.StatusWorking, Directory: "/a", RunnerPID: 999999})
+	queue(t, dir, "2021", "/a", 0, time.Now())
+
+	cfg := config.Defaults()
+	cfg.Worker.MaxConcurrent = 1
+	var launched []string
+	Promote(dir, cfg, recordLaunches(&launched))
+	if fmt.Sprint(launched) != "[2021]" {
+		t.Errorf("a dead runner should not hold a slot, got %v", launched)
+	}
+}
+
+func TestPromoteIgnoresUnclaimed(t *testing.T) {
+	dir := t.TempDir()
+	long := time.Now().Add(-2 * claimWindow)
+	recent := time.Now()
+	state.Write(dir, &state.Worker{ID: "2022", Status: state.StatusWorking, Directory: "/a", StartedAt: &long})
+	state.Write(dir, &state.Worker{ID: "2023", Status: state.StatusWorking, Directory: "/b", StartedAt: &recent})
+	queue(t, dir, "2024", "/a", 0, time.Now())
+
+	cfg := config.Defaults()
+	cfg.Worker.MaxConcurrent = 2
+	var launched []string
+	Promote(dir, cfg, recordLaunches(&launched))
+	if fmt.Sprint(launched) != "[2024]" {
+		t.Errorf("a worker no runner claimed should not hold a slot, got %v", launched)
+	}
+}
+
+func TestPromoteLaunchFailure(t *testing.T) {
+	dir := t.TempDir()
+	queue(t, dir, "2030", "/a", 0, time.Now())
+
+	Promote(dir, config.Defaults(), func(string) error { return fmt.Errorf("no ccl binary") })
+	w, _ := state.Read(dir, "2030")
+	if w.Status != state.StatusError || w.ErrorReason != "spawn worker: no ccl binary" {
+		t.Errorf("expected spawn failure recorded, got %+v", w)
+	}
+}
@@ -20,6 +20,11 @@ func SpawnRun(id, cclBin, configPath, stateDir string) error {
 		"CCL_STATE_DIR="+stateDir,
 		"CCL_CONFIG="+configPath,
 	)
-	return cmd.Start()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap it if this process outlives it, as the TUI does, so it doesn't
+	// linger as a zombie that still looks alive.
+	go cmd.Wait()
+	return nil
 }
-
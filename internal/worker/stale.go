@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// MarkStale marks the working and paused workers among workers whose
+// processes are all gone as errored, updating workers in place. A worker
+// is live while its "ccl run" supervisor is, even between attempts or
+// after the agent has exited; a paused worker's stopped processes are
+// still alive. Workers with no process recorded yet are left alone until
+// claimWindow after they were started.
+func MarkStale(stateDir string, workers []*state.Worker) {
+	live := []state.Status{state.StatusWorking, state.StatusPaused}
+	for i, w := range workers {
+		if !w.Status.In(live) || (w.PID <= 0 && w.RunnerPID <= 0 && !unclaimed(w)) || running(w) {
+			continue
+		}
+		detail := "process not running"
+		if unclaimed(w) {
+			detail = "runner never started"
+		}
+		updated, err := state.Transition(stateDir, w.ID, live, func(w *state.Worker) error {
+			// It may have moved on since it was listed.
+			if running(w) {
+				return fmt.Errorf("worker %s is running", w.ID)
+			}
+			now := time.Now()
+			w.Status = state.StatusError
+			w.PausedMS = w.PausedFor(now).Milliseconds()
+			w.PausedAt = nil
+			w.FinishedAt = &now
+			return nil
+		})
+		if err == nil {
+			workers[i] = updated
+			state.AppendEvent(stateDir, w.ID, state.Event{Type: state.EventStale, PID: w.PID, Detail: detail})
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func TestMarkStale(t *testing.T) {
+	stateDir := t.TempDir()
+	workers := []*state.Worker{
+		// The agent has exited but its supervisor is still finishing up.
+		{ID: "5000", Status: state.StatusWorking, Directory: "/tmp", Task: "t", PID: 99999, RunnerPID: os.Getpid()},
+		{ID: "5001", Status: state.StatusWorking, Directory: "/tmp", Task: "t", PID: 99999, RunnerPID: 99998},
+		// Written before the supervisor's pid was recorded.
+		{ID: "5002", Status: state.StatusPaused, Directory: "/tmp", Task: "t", PID: 99999},
+		// Not started yet.
+		{ID: "5003", Status: state.StatusWorking, Directory: "/tmp", Task: "t"},
+		{ID: "5004", Status: state.StatusDone, Directory: "/tmp", Task: "t", PID: 99999},
+	}
+	for _, w := range workers {
+		state.Write(stateDir, w)
+	}
+
+	MarkStale(stateDir, workers)
+	for id, want := range map[string]state.Status{"5000": state.StatusWorking, "5001": state.StatusError, "5002": state.StatusError, "5003": state.StatusWorking, "5004": state.StatusDone} {
+		w, _ := state.Read(stateDir, id)
+		if w.Status != want {
+			t.Errorf("worker %s: expected %s, got %s", id, want, w.Status)
+		}
+		if want == state.StatusError && w.FinishedAt == nil {
+			t.Errorf("worker %s: expected a finish time", id)
+		}
+	}
+	if workers[1].Status != state.StatusError {
+		t.Errorf("expected workers updated in place, got %s", workers[1].Status)
+	}
+}
+
+func TestMarkStaleUnclaimed(t *testing.T) {
+	stateDir := t.TempDir()
+	long := time.Now().Add(-2 * claimWindow)
+	w := &state.Worker{ID: "5005", Status: state.StatusWorking, Directory: "/tmp", Task: "t", StartedAt: &long}
+	state.Write(stateDir, w)
+
+	MarkStale(stateDir, []*state.Worker{w})
+	if w, _ := state.Read(stateDir, "5005"); w.Status != state.StatusError {
+		t.Errorf("expected a worker no runner claimed to go stale, got %s", w.Status)
+	}
+}
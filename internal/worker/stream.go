@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"bytes"
+
+	"github.com/scottstav/wreccless/internal/logrender"
+)
+
+// resultWatcher is an io.Writer that parses an agent's output with parseLine
+// as it is written and remembers the last result event. It is fed by a
+// single goroutine (exec's stdout copier) and must only be read after
+// cmd.Wait.
+type resultWatcher struct {
+	parseLine func([]byte) []logrender.Event
+	buf       []byte
+	subtype   string
+	result    *logrender.Result
+}
+
+func (rw *resultWatcher) Write(p []byte) (int, error) {
+	rw.buf = append(rw.buf, p...)
+	for {
+		i := bytes.IndexByte(rw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		rw.parse(rw.buf[:i])
+		rw.buf = rw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush parses a trailing line that wasn't newline-terminated.
+func (rw *resultWatcher) flush() {
+	if len(rw.buf) > 0 {
+		rw.parse(rw.buf)
+		rw.buf = nil
+	}
+}
+
+func (rw *resultWatcher) parse(line []byte) {
+	if !bytes.Contains(line, []byte(`"result"`)) {
+		return
+	}
+	for _, e := range rw.parseLine(line) {
+		if e.Type == logrender.EventResult {
+			rw.subtype = e.SubType
+			rw.result = e.Result
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/scottstav/wreccless/internal/git"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+// IsolationWorktree runs a worker in its own git worktree.
+const IsolationWorktree = "worktree"
+
+// Isolation returns the isolation mode to create a worker with: the
+// configured one ([worker] isolation), or a worktree if worktree is set.
+// "none" is returned as "".
+func Isolation(configured string, worktree bool) (string, error) {
+	if worktree {
+		return IsolationWorktree, nil
+	}
+	switch configured {
+	case "", "none":
+		return "", nil
+	case IsolationWorktree:
+		return configured, nil
+	}
+	return "", fmt.Errorf("unknown isolation %q (want \"worktree\" or \"none\")", configured)
+}
+
+// BranchName is the branch a worktree-isolated worker commits to.
+func BranchName(id string) string {
+	return "ccl/" + id
+}
+
+func worktreePath(stateDir, id string) string {
+	return filepath.Join(stateDir, "worktrees", id)
+}
+
+// setupWorktree creates w's worktree from the current HEAD of its directory
+// and records it, or returns the existing one. It returns the directory
+// claude should run in.
+func setupWorktree(stateDir string, w *state.Worker) (string, error) {
+	if w.WorktreePath != "" {
+		if _, err := os.Stat(w.WorktreePath); err == nil {
+			return WorkDir(w), nil
+		}
+	}
+	root, err := git.Root(w.Directory)
+	if err != nil {
+		return "", err
+	}
+	base, err := git.Head(root)
+	if err != nil {
+		return "", err
+	}
+	path := worktreePath(stateDir, w.ID)
+	branch := BranchName(w.ID)
+	if err := git.AddWorktree(root, path, branch, base); err != nil {
+		return "", err
+	}
+
+	updated, err := state.Transition(stateDir, w.ID, nil, func(w *state.Worker) error {
+		w.WorktreePath = path
+		w.Branch = branch
+		w.BaseCommit = base
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	*w = *updated
+	state.AppendEvent(stateDir, w.ID, state.Event{Type: state.EventWorktree, Detail: fmt.Sprintf("created %s on %s at %.12s", path, branch, base)})
+	return WorkDir(w), nil
+}
+
+// WorkDir is the directory claude runs in for w: the matching subdirectory
+// of its worktree if it has one, otherwise its Directory.
+func WorkDir(w *state.Worker) string {
+	if w.WorktreePath == "" {
+		return w.Directory
+	}
+	root, err := git.Root(w.Directory)
+	if err != nil {
+		return w.WorktreePath
+	}
+	rel, err := filepath.Rel(root, w.Directory)
+	if err != nil || rel == "." {
+		return w.WorktreePath
+	}
+	return filepath.Join(w.WorktreePath, rel)
+}
+
+// RemoveWorktree deletes w's worktree, keeping its branch, and clears it
+// from w's state if w is still live. Unless force is set, a worktree with
+// uncommitted changes is left alone and an error returned.
+func RemoveWorktree(stateDir string, w *state.Worker, force bool) error {
+	if w.WorktreePath == "" {
+		return fmt.Errorf("worker %s has no worktree", w.ID)
+	}
+	root, err := git.Root(w.Directory)
+	if err != nil {
+		return err
+	}
+	if _, statErr := os.Stat(w.WorktreePath); os.IsNotExist(statErr) {
+		if err := git.PruneWorktrees(root); err != nil {
+			return err
+		}
+	} else if err := git.RemoveWorktree(root, w.WorktreePath, force); err != nil {
+		if !force {
+			return fmt.Errorf("worker %s: %w (use --force to discard)", w.ID, err)
+		}
+		return err
+	}
+
+	state.AppendEvent(stateDir, w.ID, state.Event{Type: state.EventWorktree, Detail: "removed " + w.WorktreePath + ", kept branch " + w.Branch})
+	if !w.Archived {
+		state.Transition(stateDir, w.ID, nil, func(w *state.Worker) error {
+			w.WorktreePath = ""
+			return nil
+		})
+	}
+	w.WorktreePath = ""
+	return nil
+}
+
+// Archive is state.Archive that first removes the worker's worktree. A
+// worktree with uncommitted changes stops the worker from being archived.
+func Archive(stateDir, id string, from []state.Status) (*state.Worker, error) {
+	if err := releaseWorktree(stateDir, id, from); err != nil {
+		return nil, err
+	}
+	return state.Archive(stateDir, id, from)
+}
+
+// Remove is state.Remove that first removes the worker's worktree and then
+// the refs pinning its commits. A worktree with uncommitted changes stops
+// the worker from being removed.
+func Remove(stateDir, id string, from []state.Status) (*state.Worker, error) {
+	if err := releaseWorktree(stateDir, id, from); err != nil {
+		return nil, err
+	}
+	w, err := state.Remove(stateDir, id, from)
+	if err == nil {
+		dropSnapshots(w)
+	}
+	return w, err
+}
+
+func releaseWorktree(stateDir, id string, from []state.Status) error {
+	w, err := state.ReadAny(stateDir, id)
+	if err != nil {
+		return err
+	}
+	// Leave the status check and its error to the state package.
+	if !w.Status.In(from) || w.WorktreePath == "" {
+		return nil
+	}
+	// Its agent may still be writing there.
+	if !w.Status.Finished() {
+		return fmt.Errorf("worker %s is %s; wait for it to finish or kill it first", id, w.Status)
+	}
+	return RemoveWorktree(stateDir, w, false)
+}
@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scottstav/wreccless/internal/config"
+	"github.com/scottstav/wreccless/internal/state"
+)
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "-q")
+	gitRun(t, dir, "-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "--allow-empty", "-m", "init")
+	return dir
+}
+
+func TestRunInWorktree(t *testing.T) {
+	stateDir := t.TempDir()
+	repo := initRepo(t)
+	script := filepath.Join(t.TempDir(), "pwd-claude")
+	os.WriteFile(script, []byte("#!/bin/sh\npwd > where.txt\n"), 0755)
+
+	w := &state.Worker{ID: "3000", Status: state.StatusWorking, Directory: repo, Task: "isolate", SessionID: "s", Isolation: IsolationWorktree}
+	state.Write(stateDir, w)
+	if err := Run(stateDir, "3000", config.Defaults(), script); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	u, _ := state.Read(stateDir, "3000")
+	if u.Status != state.StatusDone || u.Branch != "ccl/3000" || len(u.BaseCommit) != 40 {
+		t.Fatalf("worktree not recorded: %+v", u)
+	}
+	if u.WorktreePath != filepath.Join(stateDir, "worktrees", "3000") {
+		t.Errorf("worktree path = %s", u.WorktreePath)
+	}
+	where, _ := os.ReadFile(filepath.Join(u.WorktreePath, "where.txt"))
+	if strings.TrimSpace(string(where)) != u.WorktreePath {
+		t.Errorf("claude ran in %q, want the worktree", where)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "where.txt")); !os.IsNotExist(err) {
+		t.Error("claude should not touch the original checkout")
+	}
+
+	// where.txt is uncommitted, so archiving must not throw it away.
+	if _, err := Archive(stateDir, "3000", state.FinishedStatuses); err == nil {
+		t.Fatal("expected archive to refuse a dirty worktree")
+	}
+	if err := RemoveWorktree(stateDir, u, true); err != nil {
+		t.Fatalf("RemoveWorktree: %v", err)
+	}
+	if _, err := Archive(stateDir, "3000", state.FinishedStatuses); err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+}
+
+func TestRunWorktreeOutsideRepo(t *testing.T) {
+	stateDir := t.TempDir()
+	w := &state.Worker{ID: "3001", Status: state.StatusWorking, Directory: t.TempDir(), Task: "no repo", SessionID: "s", Isolation: IsolationWorktree}
+	state.Write(stateDir, w)
+	if err := Run(stateDir, "3001", config.Defaults(), "/bin/true"); err == nil {
+		t.Fatal("expected worktree setup to fail outside a repository")
+	}
+	u, _ := state.Read(stateDir, "3001")
+	if u.Status != state.StatusError || !strings.HasPrefix(u.ErrorReason, "worktree:") {
+		t.Errorf("expected worktree error, got %+v", u)
+	}
+}
+
+func TestIsolation(t *testing.T) {
+	for _, tc := range []struct {
+		configured string
+		worktree   bool
+		want       string
+	}{
+		{"", false, ""},
+		{"none", false, ""},
+		{"worktree", false, IsolationWorktree},
+		{"none", true, IsolationWorktree},
+	} {
+		if got, err := Isolation(tc.configured, tc.worktree); err != nil || got != tc.want {
+			t.Errorf("Isolation(%q, %v) = %q, %v; want %q", tc.configured, tc.worktree, got, err, tc.want)
+		}
+	}
+	if _, err := Isolation("container", false); err == nil {
+		t.Error("expected an error for an unknown isolation mode")
+	}
+}
+
+func TestArchiveKeepsLiveWorktree(t *testing.T) {
+	stateDir := t.TempDir()
+	repo := initRepo(t)
+	wt := filepath.Join(t.TempDir(), "wt")
+	gitRun(t, repo, "worktree", "add", "-q", "-b", "ccl/3002", wt)
+	state.Write(stateDir, &state.Worker{ID: "3002", Status: state.StatusWorking, Directory: repo, Task: "t", WorktreePath: wt, Branch: "ccl/3002"})
+
+	// As "ccl clean --all" does, with no status restriction.
+	if _, err := Archive(stateDir, "3002", nil); err == nil || !strings.Contains(err.Error(), "is working") {
+		t.Errorf("expected a refusal, got %v", err)
+	}
+	if _, err := os.Stat(wt); err != nil {
+		t.Errorf("worktree of a working worker was removed: %v", err)
+	}
+	if w, err := state.Read(stateDir, "3002"); err != nil || w.WorktreePath != wt {
+		t.Errorf("expected the working worker kept with its worktree, got %+v, %v", w, err)
+	}
+}